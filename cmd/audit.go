@@ -0,0 +1,132 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+	"time"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit the state of all Consistency Groups",
+	Long: `Audit the state of all Consistency Groups
+examples:
+
+rpda audit stale --older-than 24h
+
+	`,
+}
+
+// auditStaleCmd represents the audit stale command
+var auditStaleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "Report copies left in image or direct access",
+	Long: `Report copies left in image or direct access
+
+All consistency groups are scanned for copies with image access enabled, whether
+enabled by rpda or otherwise (ie: via the GUI). The time in access of copies enabled
+by rpda is taken from the audit log (or lease). For other copies the age of the
+accessed image is used instead, as the image was taken before image access was
+enabled. Copies in access for longer than --older-than (or for an unknown time) are
+marked as stale along with the journal space remaining & any rpda lease.
+
+Use --fail to exit with a non-zero status when stale copies are found (ie: for
+monitoring).
+
+examples:
+
+rpda audit stale
+
+rpda audit stale --older-than 24h --sort journal
+
+rpda audit stale --older-than 72h --fail
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		olderThan, err := cmd.Flags().GetDuration("older-than")
+		if err != nil {
+			log.Fatal(err)
+		}
+		sortBy, err := cmd.Flags().GetString("sort")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fail, err := cmd.Flags().GetBool("fail")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("audit stale command 'older-than' flag value: ", olderThan)
+		log.Debug("audit stale command 'sort' flag value: ", sortBy)
+		log.Debug("audit stale command 'fail' flag value: ", fail)
+
+		stale, err := a.AuditStale(olderThan, sortBy)
+		if err != nil {
+			log.Error(err)
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if fail && stale > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditStaleCmd)
+
+	// command flags and configuration settings.
+	auditStaleCmd.PersistentFlags().Duration("older-than", 24*time.Hour, "Mark copies in access for longer than this duration as stale")
+	auditStaleCmd.PersistentFlags().String("sort", "age", "Sort the report by age, journal or group")
+	auditStaleCmd.PersistentFlags().Bool("fail", false, "Exit with a non-zero status when stale copies are found")
+}
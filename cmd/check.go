@@ -0,0 +1,145 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Monitoring checks (Nagios/Icinga compatible)",
+	Long: `Monitoring checks (Nagios/Icinga compatible)
+examples:
+
+rpda check rpo --all --max 15m
+
+	`,
+}
+
+// checkRPOCmd represents the check rpo command
+var checkRPOCmd = &cobra.Command{
+	Use:   "rpo",
+	Short: "Check the current lag of each copy against an RPO threshold",
+	Long: `Check the current lag of each copy against an RPO threshold
+
+The threshold for each copy is taken from (in order of precedence):
+ - the 'rpo.groups' section of the configuration file
+ - the --max flag (or 'rpo.max' in the configuration file)
+ - the RPO configured on the link of the copy
+
+The warning threshold defaults to 80% of the threshold unless --warn is provided.
+Output & exit codes follow the Nagios/Icinga plugin conventions:
+0 (OK), 1 (WARNING), 2 (CRITICAL) & 3 (UNKNOWN)
+
+examples:
+
+rpda check rpo --all
+
+rpda check rpo --all --max 15m
+
+rpda check rpo --group Example_CG --max 15m --warn 10m
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		group, err := cmd.Flags().GetString("group")
+		if err != nil {
+			log.Fatal(err)
+		}
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			log.Fatal(err)
+		}
+		max, err := cmd.Flags().GetDuration("max")
+		if err != nil {
+			log.Fatal(err)
+		}
+		warn, err := cmd.Flags().GetDuration("warn")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("check rpo command 'group' flag value: ", group)
+		log.Debug("check rpo command 'all' flag value: ", all)
+		log.Debug("check rpo command 'max' flag value: ", max)
+		log.Debug("check rpo command 'warn' flag value: ", warn)
+
+		// ensure group or all flags were provided
+		if all == false && group == "" {
+			log.Error("Either --all or --group must be specified.")
+			cmd.Usage()
+			os.Exit(rpa.CheckUnknown)
+		}
+
+		// flags override thresholds from the configuration file
+		if max > 0 {
+			a.Config.RPOMax = max
+		}
+		if warn > 0 {
+			a.Config.RPOWarning = warn
+		}
+
+		a.Group = group
+
+		os.Exit(a.CheckRPO())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.AddCommand(checkRPOCmd)
+
+	// command flags and configuration settings.
+	checkRPOCmd.PersistentFlags().Bool("all", false, "Check All Consistency Groups")
+	checkRPOCmd.PersistentFlags().String("group", "", "Check Consistency Group by Name")
+	checkRPOCmd.PersistentFlags().Duration("max", 0, "Maximum Lag before CRITICAL (ie: 15m)")
+	checkRPOCmd.PersistentFlags().Duration("warn", 0, "Maximum Lag before WARNING (default: 80% of --max)")
+}
@@ -0,0 +1,110 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff BEFORE.json [AFTER.json]",
+	Short: "Compare saved status snapshots",
+	// saved snapshots are compared locally, the api is only used with --live
+	Annotations: map[string]string{"offline": "true", "online_with": "live"},
+	Long: `Compare saved status snapshots
+
+Reports consistency groups & copies added or removed and changes to the role,
+image access, storage access & transfer state of each copy between two snapshots
+saved with 'rpda snapshot save'. With --live, the snapshot is compared with the
+current state.
+
+Exit codes: 0 (no differences), 1 (differences found) & 2 (error)
+
+examples:
+
+rpda diff before.json after.json
+
+rpda diff before.json --live
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		live, err := cmd.Flags().GetBool("live")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("diff command 'live' flag value: ", live)
+		log.Debug("diff command args: ", args)
+
+		// preflight checks
+
+		// ensure two snapshots or a snapshot and --live were provided
+		if (live && len(args) != 1) || (!live && len(args) != 2) {
+			log.Error("Either two snapshot files or one snapshot file and --live must be provided")
+			cmd.Usage()
+			os.Exit(2)
+		}
+
+		after := ""
+		if !live {
+			after = args[1]
+		}
+
+		os.Exit(a.Diff(args[0], after))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	// command flags and configuration settings.
+	diffCmd.PersistentFlags().Bool("live", false, "Compare with the current state")
+}
@@ -57,6 +57,20 @@ rpda enable --all --test
 
 rpda enable --all --dr
 
+rpda enable --app ERP --test
+
+rpda enable --all --test --rollback-on-failure --max-failures 3
+
+rpda enable --all --test --canary 2 --yes
+
+rpda enable --group EXAMPLE_CG --test --lease 4h
+
+rpda enable --all --test --max-journal-usage 90
+
+rpda enable --all --test --ticket CHG0042 --report drill.html
+
+rpda enable --all --test --junit drill.xml
+
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 
@@ -92,35 +106,133 @@ rpda enable --all --dr
 		if err != nil {
 			log.Fatal(err)
 		}
+		app, err := cmd.Flags().GetString("app")
+		if err != nil {
+			log.Fatal(err)
+		}
+		canary, err := cmd.Flags().GetInt("canary")
+		if err != nil {
+			log.Fatal(err)
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			log.Fatal(err)
+		}
+		lease, err := cmd.Flags().GetDuration("lease")
+		if err != nil {
+			log.Fatal(err)
+		}
+		maxJournalUsage, err := cmd.Flags().GetFloat64("max-journal-usage")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		rollbackOnFailure, err := cmd.Flags().GetBool("rollback-on-failure")
+		if err != nil {
+			log.Fatal(err)
+		}
+		maxFailures, err := cmd.Flags().GetInt("max-failures")
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err := cmd.Flags().GetString("report")
+		if err != nil {
+			log.Fatal(err)
+		}
+		junit, err := cmd.Flags().GetString("junit")
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		log.Debug("enable command 'group' flag value: ", group)
 		log.Debug("enable command 'copy' flag value: ", copyByName)
 		log.Debug("enable command 'test' flag value: ", testCopy)
 		log.Debug("enable command 'dr' flag value: ", drCopy)
 		log.Debug("enable command 'all' flag value: ", all)
+		log.Debug("enable command 'app' flag value: ", app)
+		log.Debug("enable command 'canary' flag value: ", canary)
+		log.Debug("enable command 'yes' flag value: ", yes)
+		log.Debug("enable command 'lease' flag value: ", lease)
+		log.Debug("enable command 'max-journal-usage' flag value: ", maxJournalUsage)
+		log.Debug("enable command 'rollback-on-failure' flag value: ", rollbackOnFailure)
+		log.Debug("enable command 'max-failures' flag value: ", maxFailures)
+		log.Debug("enable command 'report' flag value: ", report)
+		log.Debug("enable command 'junit' flag value: ", junit)
 
 		// preflight checks
 
-		// ensure group or all flags were provided
-		if all == false && group == "" {
-			log.Error("Either --all or --group must be specified.")
+		// ensure exactly one of the group, all or app flags were provided
+		if all == false && group == "" && app == "" {
+			log.Error("Either --all, --group or --app must be specified.")
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if (all == true && (group != "" || app != "")) || (group != "" && app != "") {
+			log.Error("Only one of --all, --group or --app can be specified.")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// if --all or --app flag was specified, --copy cannot be used
+		if (all == true || app != "") && copyByName != "" {
+			log.Error("--copy cannot be used with --all or --app")
 			cmd.Usage()
 			os.Exit(1)
 		}
 
-		// if --all flag was specified, --copy cannot be used
-		if all == true && copyByName != "" {
-			log.Error("--copy cannot be used with --all")
+		// canary rollouts only apply to --all
+		if all == false && (canary != 0 || yes == true) {
+			log.Error("--canary and --yes can only be used with --all")
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if canary < 0 {
+			log.Error("--canary cannot be negative")
 			cmd.Usage()
 			os.Exit(1)
 		}
 
+		if lease < 0 {
+			log.Error("--lease cannot be negative")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// rollback & failure thresholds only apply to --all
+		if all == false && (rollbackOnFailure == true || maxFailures != 0) {
+			log.Error("--rollback-on-failure and --max-failures can only be used with --all")
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if maxFailures < 0 {
+			log.Error("--max-failures cannot be negative")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// the report format is taken from the extension of the report file
+		if report != "" {
+			if _, err := rpa.ReportFormat(report); err != nil {
+				log.Error(err)
+				cmd.Usage()
+				os.Exit(1)
+			}
+		}
+
 		a.Group = group
 		a.CopyName = copyByName
+		a.RollbackOnFailure = rollbackOnFailure
+		a.MaxFailures = maxFailures
+		a.Canary = canary
+		a.Yes = yes
+		a.Lease = lease
+		a.MaxJournalUsage = maxJournalUsage
+		a.Report = report
+		a.JUnit = junit
 
 		// if an exact copy name was not provided, ensure an image copy flag was provided
 		if copyByName == "" && testCopy == false && drCopy == false {
-			if all {
+			if all || app != "" {
 				log.Error("One of --test or --dr must be specified")
 			} else {
 				log.Error("One of --test --dr or --copy must be specified")
@@ -145,10 +257,19 @@ rpda enable --all --dr
 
 		if group != "" {
 			// display status of single group if a group name was provided
-			a.EnableOne()
+			if err := a.EnableOne(); err != nil {
+				os.Exit(1)
+			}
 		} else if all {
 			// display status of all groups if the --all flag was provided
-			a.EnableAll()
+			if err := a.EnableAll(); err != nil {
+				os.Exit(1)
+			}
+		} else if app != "" {
+			// operate on the groups of an application in dependency order
+			if err := a.EnableApp(app); err != nil {
+				os.Exit(1)
+			}
 		} else {
 			// otherwise, display command usage
 			cmd.Usage()
@@ -163,7 +284,16 @@ func init() {
 	// command flags and configuration settings.
 	enableCmd.PersistentFlags().Bool("all", false, "Enable Direct Image Access for All Consistency Groups")
 	enableCmd.PersistentFlags().String("group", "", "Enable Direct Image Access for Consistency Group by Name")
+	enableCmd.PersistentFlags().String("app", "", "Enable Direct Image Access for an Application (see 'applications' in config)")
 	enableCmd.PersistentFlags().String("copy", "", "Use Latest Test Copy Image By Name (only usable with --group)")
 	enableCmd.PersistentFlags().Bool("test", false, "Use Latest Test Copy Image")
 	enableCmd.PersistentFlags().Bool("dr", false, "Use Latest DR Copy Image")
+	enableCmd.PersistentFlags().Int("canary", 0, "Verify the first N groups of --all before continuing with the remaining groups")
+	enableCmd.PersistentFlags().Bool("yes", false, "Continue after canary verification without confirmation")
+	enableCmd.PersistentFlags().Duration("lease", 0, "Finish the copy with 'rpda leases reap' once the lease expires (ie: 4h)")
+	enableCmd.PersistentFlags().Float64("max-journal-usage", 0, "Refuse to enable copies with a journal usage percent at or above this value")
+	enableCmd.PersistentFlags().Bool("rollback-on-failure", false, "Finish groups enabled by this run when --all stops due to failures")
+	enableCmd.PersistentFlags().String("report", "", "Write a report of the run before/after state & steps (report.html, report.md or report.json)")
+	enableCmd.PersistentFlags().String("junit", "", "Write the results of the run as junit xml with a test case for each step of each group")
+	enableCmd.PersistentFlags().Int("max-failures", 0, "Stop --all once this many groups have failed (default: no limit, 1 with --rollback-on-failure)")
 }
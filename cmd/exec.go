@@ -0,0 +1,151 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec -- COMMAND [ARGS...]",
+	Short: "Enable direct access, run a command, then always finish",
+	Long: `Enable direct access, run a command, then always finish
+
+Direct access is enabled for the latest copy image, the command is run and the copy
+is returned to replication even if the command fails or rpda is interrupted (Ctrl-C).
+The exit status of the command is returned.
+
+The following environment variables are provided to the command:
+RPDA_GROUP, RPDA_GROUP_UID, RPDA_CLUSTER_UID, RPDA_COPY, RPDA_COPY_UID &
+RPDA_IMAGE_TIMESTAMP (RFC3339)
+
+examples:
+
+rpda exec --group EXAMPLE_CG --test -- ./verify.sh
+
+rpda exec --group EXAMPLE_CG --copy Test_Copy -- ./verify.sh --quick
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		group, err := cmd.Flags().GetString("group")
+		if err != nil {
+			log.Fatal(err)
+		}
+		copyByName, err := cmd.Flags().GetString("copy")
+		if err != nil {
+			log.Fatal(err)
+		}
+		testCopy, err := cmd.Flags().GetBool("test")
+		if err != nil {
+			log.Fatal(err)
+		}
+		drCopy, err := cmd.Flags().GetBool("dr")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("exec command 'group' flag value: ", group)
+		log.Debug("exec command 'copy' flag value: ", copyByName)
+		log.Debug("exec command 'test' flag value: ", testCopy)
+		log.Debug("exec command 'dr' flag value: ", drCopy)
+		log.Debug("exec command args: ", args)
+
+		// preflight checks
+
+		// ensure a group was provided
+		if group == "" {
+			log.Error("--group must be specified.")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// ensure a command was provided
+		if len(args) == 0 {
+			log.Error("A command must be provided after --")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// ensure exactly one image copy flag was provided
+		if copyByName == "" && testCopy == false && drCopy == false {
+			log.Error("One of --test --dr or --copy must be specified")
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if (copyByName != "" && (testCopy == true || drCopy == true)) || (testCopy == true && drCopy == true) {
+			log.Error("Only one of --test --dr or --copy can be specified")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		a.Group = group
+		a.CopyName = copyByName
+
+		if drCopy == true {
+			a.CopyRegexp = a.Identifiers.CopyNodeRegexp
+		}
+		if testCopy == true {
+			a.CopyRegexp = a.Identifiers.TestNodeRegexp
+		}
+
+		os.Exit(a.Exec(args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// command flags and configuration settings.
+	execCmd.PersistentFlags().String("group", "", "Consistency Group by Name")
+	execCmd.PersistentFlags().String("copy", "", "Use Latest Copy Image By Name")
+	execCmd.PersistentFlags().Bool("test", false, "Use Latest Test Copy Image")
+	execCmd.PersistentFlags().Bool("dr", false, "Use Latest DR Copy Image")
+}
@@ -0,0 +1,117 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+	"time"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// exporterCmd represents the exporter command
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Serve Consistency Group metrics for Prometheus",
+	Long: `Serve Consistency Group metrics for Prometheus
+
+The state of all consistency groups & copies is collected every --interval and
+cached between collections. Metrics are served at http://<listen>/metrics
+
+examples:
+
+rpda exporter
+
+rpda exporter --listen :9650 --interval 2m
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		listen, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			log.Fatal(err)
+		}
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("exporter command 'listen' flag value: ", listen)
+		log.Debug("exporter command 'interval' flag value: ", interval)
+
+		// flags override the configuration file, which overrides the defaults
+		if listen == "" {
+			listen = a.Config.ExporterListen
+		}
+		if listen == "" {
+			listen = ":9650"
+		}
+		if cmd.Flags().Changed("interval") {
+			// ensure the api is not polled continuously
+			if interval <= 0 {
+				log.Error("--interval must be greater than 0")
+				cmd.Usage()
+				os.Exit(1)
+			}
+		} else {
+			interval = a.Config.ExporterInterval
+		}
+		if interval <= 0 {
+			interval = time.Minute
+		}
+
+		a.ServeMetrics(listen, interval)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exporterCmd)
+
+	// command flags and configuration settings.
+	exporterCmd.PersistentFlags().String("listen", "", "Address to serve metrics on (default: ':9650')")
+	exporterCmd.PersistentFlags().Duration("interval", 0, "Time between metric collections (default: 1m)")
+}
@@ -51,6 +51,10 @@ rpda finish --group EXAMPLE_CG --test
 
 rpda finish --all --test
 
+rpda finish --all --test --canary 2
+
+rpda finish --app ERP --test
+
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 
@@ -86,35 +90,90 @@ rpda finish --all --test
 		if err != nil {
 			log.Fatal(err)
 		}
+		app, err := cmd.Flags().GetString("app")
+		if err != nil {
+			log.Fatal(err)
+		}
+		canary, err := cmd.Flags().GetInt("canary")
+		if err != nil {
+			log.Fatal(err)
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err := cmd.Flags().GetString("report")
+		if err != nil {
+			log.Fatal(err)
+		}
+		junit, err := cmd.Flags().GetString("junit")
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		log.Debug("finish command 'group' flag value: ", group)
 		log.Debug("enable command 'copy' flag value: ", copyByName)
 		log.Debug("finish command 'test' flag value: ", testCopy)
 		log.Debug("finish command 'dr' flag value: ", drCopy)
 		log.Debug("finish command 'all' flag value: ", all)
+		log.Debug("finish command 'app' flag value: ", app)
+		log.Debug("finish command 'canary' flag value: ", canary)
+		log.Debug("finish command 'yes' flag value: ", yes)
+		log.Debug("finish command 'report' flag value: ", report)
+		log.Debug("finish command 'junit' flag value: ", junit)
 
 		// preflight checks
 
-		// ensure group or all flags were provided
-		if all == false && group == "" {
-			log.Error("Either --all or --group must be specified.")
+		// ensure exactly one of the group, all or app flags were provided
+		if all == false && group == "" && app == "" {
+			log.Error("Either --all, --group or --app must be specified.")
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if (all == true && (group != "" || app != "")) || (group != "" && app != "") {
+			log.Error("Only one of --all, --group or --app can be specified.")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// if --all or --app flag was specified, --copy cannot be used
+		if (all == true || app != "") && copyByName != "" {
+			log.Error("--copy cannot be used with --all or --app")
 			cmd.Usage()
 			os.Exit(1)
 		}
 
-		// if --all flag was specified, --copy cannot be used
-		if all == true && copyByName != "" {
-			log.Error("--copy cannot be used with --all")
+		// canary rollouts only apply to --all
+		if all == false && (canary != 0 || yes == true) {
+			log.Error("--canary and --yes can only be used with --all")
+			cmd.Usage()
+			os.Exit(1)
+		}
+		if canary < 0 {
+			log.Error("--canary cannot be negative")
 			cmd.Usage()
 			os.Exit(1)
 		}
 
+		// the report format is taken from the extension of the report file
+		if report != "" {
+			if _, err := rpa.ReportFormat(report); err != nil {
+				log.Error(err)
+				cmd.Usage()
+				os.Exit(1)
+			}
+		}
+
 		a.Group = group
 		a.CopyName = copyByName
+		a.Canary = canary
+		a.Yes = yes
+		a.Report = report
+		a.JUnit = junit
 
 		// if an exact copy name provided, ensure A image copy flag provided
 		if copyByName == "" && testCopy == false && drCopy == false {
-			if all {
+			if all || app != "" {
 				log.Error("One of --test or --dr must be specified")
 			} else {
 				log.Error("One of --test --dr or --copy must be specified")
@@ -139,10 +198,19 @@ rpda finish --all --test
 
 		if group != "" {
 			// display status of single group if a group name was provided
-			a.FinishOne()
+			if err := a.FinishOne(); err != nil {
+				os.Exit(1)
+			}
 		} else if all {
 			// display status of all groups if the all flag was provided
-			a.FinishAll()
+			if err := a.FinishAll(); err != nil {
+				os.Exit(1)
+			}
+		} else if app != "" {
+			// operate on the groups of an application in dependency order
+			if err := a.FinishApp(app); err != nil {
+				os.Exit(1)
+			}
 		} else {
 			// otherwise, display command usage
 			cmd.Usage()
@@ -157,7 +225,12 @@ func init() {
 	// command flags and configuration settings.
 	finishCmd.PersistentFlags().Bool("all", false, "Display Status for All Consistency Groups")
 	finishCmd.PersistentFlags().String("group", "", "Display Status of Consistency Group by Name")
+	finishCmd.PersistentFlags().String("app", "", "Finish Direct Image Access for an Application (see 'applications' in config)")
 	finishCmd.PersistentFlags().String("copy", "", "Use Latest Test Copy Image By Name (only usable with --group)")
 	finishCmd.PersistentFlags().Bool("test", false, "Use Latest Test Copy Image")
 	finishCmd.PersistentFlags().Bool("dr", false, "Use Latest DR Copy Image")
+	finishCmd.PersistentFlags().Int("canary", 0, "Verify the first N groups of --all before continuing with the remaining groups")
+	finishCmd.PersistentFlags().Bool("yes", false, "Continue after canary verification without confirmation")
+	finishCmd.PersistentFlags().String("report", "", "Write a report of the run before/after state & steps (report.html, report.md or report.json)")
+	finishCmd.PersistentFlags().String("junit", "", "Write the results of the run as junit xml with a test case for each step of each group")
 }
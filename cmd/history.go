@@ -0,0 +1,198 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query the audit log",
+	// the audit log is local, the api is not used
+	Annotations: map[string]string{"offline": "true"},
+	Long: `Query the audit log
+
+Displays the changes recorded in the audit log (see 'audit.path' in config).
+Filters can be combined; --action matches part of the action name (ie: direct_access
+matches enable_direct_access & disable_direct_access), --user matches either the os
+or rpa user and --ticket matches the change ticket. Completed runs are listed with --action run and can be displayed step by step with 'rpda history show'.
+
+examples:
+
+rpda history --since 7d
+
+rpda history --group ERP_DB_CG --action direct_access --since 2020-04-14
+
+rpda history --user jdoe --ticket CHG0042 --json
+
+rpda history --action run
+
+rpda history show 20200420-220014-80dd
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		group, err := cmd.Flags().GetString("group")
+		if err != nil {
+			log.Fatal(err)
+		}
+		since, err := cmd.Flags().GetString("since")
+		if err != nil {
+			log.Fatal(err)
+		}
+		// --user & --ticket are inherited from the root command, which does not use them for history
+		user, err := cmd.Flags().GetString("user")
+		if err != nil {
+			log.Fatal(err)
+		}
+		ticket, err := cmd.Flags().GetString("ticket")
+		if err != nil {
+			log.Fatal(err)
+		}
+		action, err := cmd.Flags().GetString("action")
+		if err != nil {
+			log.Fatal(err)
+		}
+		asJSON, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("history command 'group' flag value: ", group)
+		log.Debug("history command 'since' flag value: ", since)
+		log.Debug("history command 'user' flag value: ", user)
+		log.Debug("history command 'ticket' flag value: ", ticket)
+		log.Debug("history command 'action' flag value: ", action)
+		log.Debug("history command 'json' flag value: ", asJSON)
+
+		f := rpa.HistoryFilter{
+			Group:  group,
+			User:   user,
+			Ticket: ticket,
+			Action: action,
+		}
+		if since != "" {
+			f.Since, err = rpa.ParseSince(since)
+			if err != nil {
+				log.Error(err)
+				cmd.Usage()
+				os.Exit(1)
+			}
+		}
+
+		a.DisplayHistory(f, asJSON)
+	},
+}
+
+// historyShowCmd represents the history show command
+var historyShowCmd = &cobra.Command{
+	Use:   "show RUN_ID",
+	Short: "Display every step of a run",
+	// the audit log & journal are local, the api is not used
+	Annotations: map[string]string{"offline": "true"},
+	Long: `Display every step of a run
+
+Displays the user, command, ticket & reason of a run along with every step performed
+on each consistency group (with timings & hook output) and the api requests made.
+
+examples:
+
+rpda history show 20200420-220014-80dd
+
+rpda history show 20200420-220014-80dd --json
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		asJSON, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("history show command 'json' flag value: ", asJSON)
+		log.Debug("history show command args: ", args)
+
+		// ensure a run id was provided
+		if len(args) != 1 {
+			log.Error("A run id must be provided")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		if err := a.DisplayRun(args[0], asJSON); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+
+	// command flags and configuration settings.
+	historyCmd.Flags().String("group", "", "Only Display Changes to a Consistency Group")
+	historyCmd.Flags().String("since", "", "Only Display Changes Since a Duration or Date (ie: 7d, 36h or 2020-04-20)")
+	historyCmd.Flags().String("action", "", "Only Display Actions Containing a Value (ie: enable, direct_access or run)")
+	historyCmd.PersistentFlags().Bool("json", false, "Display as JSON")
+}
@@ -36,6 +36,7 @@ import (
 	"os"
 	"syscall"
 
+	"github.com/bcambl/rpda/internal/pkg/rpa"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh/terminal"
 
@@ -54,6 +55,10 @@ var (
 	delayFlag     int
 	pollDelayFlag int
 	pollMaxFlag   int
+
+	overrideWindowFlag bool
+	reasonFlag         string
+	ticketFlag         string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -115,6 +120,9 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(&delayFlag, "delay", 0, "Seconds to wait between Consistency Groups with --all")
 	rootCmd.PersistentFlags().IntVar(&pollDelayFlag, "polldelay", 3, "Seconds to wait between API status polling requests")
 	rootCmd.PersistentFlags().IntVar(&pollMaxFlag, "pollmax", 30, "Number of status poll attempts with before failing")
+	rootCmd.PersistentFlags().BoolVar(&overrideWindowFlag, "override-window", false, "Allow changes outside of a maintenance window (requires --reason)")
+	rootCmd.PersistentFlags().StringVar(&reasonFlag, "reason", "", "Reason for the change (recorded in the journal & audit log)")
+	rootCmd.PersistentFlags().StringVar(&ticketFlag, "ticket", "", "Change ticket reference (recorded in the journal & audit log)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -185,14 +193,31 @@ func initConfig() {
 		}
 	}
 
-	fmt.Println("Using config file: ", viper.ConfigFileUsed())
+	// written to stderr to keep stdout clean for machine readable output (checks, json..)
+	fmt.Fprintln(os.Stderr, "Using config file: ", viper.ConfigFileUsed())
+
+	// add the logging backends (json, syslog & log file) from config
+	rpa.ConfigureLogging()
 
 	// add check and debug flags to viper
 	viper.Set("check", checkFlag)
 	viper.Set("debug", debugFlag)
 	viper.Set("api.delay", delayFlag)
 	viper.Set("api.polldelay", pollDelayFlag)
-	viper.Set("api.pollmax", pollDelayFlag)
+	viper.Set("api.pollmax", pollMaxFlag)
+	viper.Set("override_window", overrideWindowFlag)
+	viper.Set("reason", reasonFlag)
+	viper.Set("ticket", ticketFlag)
+
+	// a reason must be recorded when overriding maintenance windows
+	if overrideWindowFlag && reasonFlag == "" {
+		log.Fatal("--reason is required with --override-window")
+	}
+
+	// commands which only read local files do not require the api
+	if offlineCommand() {
+		return
+	}
 
 	// test for default url & username
 	if viper.Get("api.url") == defaultURL || viper.Get("api.username") == defaultUsername {
@@ -210,6 +235,22 @@ func initConfig() {
 	passwordPrompt()
 }
 
+// offlineCommand reports whether the command being run does not use the api. Commands are marked
+// offline with the "offline" annotation (ie: history reads the local audit log) and the "online_with"
+// annotation names a flag which requires the api (ie: diff --live).
+func offlineCommand() bool {
+	cmd, _, err := rootCmd.Find(os.Args[1:])
+	if err != nil {
+		return false
+	}
+	if flag := cmd.Annotations["online_with"]; flag != "" {
+		if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+			return false
+		}
+	}
+	return cmd.Annotations["offline"] == "true"
+}
+
 func passwordPrompt() {
 	// password _can_ be saved to the config file; however, prompt by default.
 	// consider this a hidden feature as passwords should not be stored in in plain text.
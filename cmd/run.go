@@ -0,0 +1,145 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run RUNBOOK",
+	Short: "Execute a DR drill runbook",
+	Long: `Execute a DR drill runbook
+
+A runbook is a yaml file of ordered stages. Each stage selects consistency groups
+(by name and/or regexp) and a copy ('test', 'dr' or a copy name), then performs its
+actions in order: bookmark, enable, wait, exec, pause & finish.
+
+The runbook is validated against the live consistency group inventory before any
+changes are made. Use --check to display the full plan without making changes.
+
+example runbook:
+
+name: Quarterly DR drill
+stages:
+  - name: databases
+    groups: [ERP_DB_CG]
+    copy: test
+    timeout: 30m
+    on_failure: rollback   # abort (default), continue or rollback
+    actions:
+      - action: bookmark
+        name: drill-start
+      - action: enable
+      - action: wait
+        duration: 30s
+      - action: exec
+        command: [./verify.sh, --quick]
+      - action: pause
+        message: Confirm with the application team
+      - action: finish
+
+examples:
+
+rpda run drill.yaml --check
+
+rpda run drill.yaml
+
+rpda run drill.yaml --ticket CHG0042 --report drill.html
+
+rpda run drill.yaml --junit drill.xml
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		report, err := cmd.Flags().GetString("report")
+		if err != nil {
+			log.Fatal(err)
+		}
+		junit, err := cmd.Flags().GetString("junit")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("run command 'report' flag value: ", report)
+		log.Debug("run command 'junit' flag value: ", junit)
+		log.Debug("run command args: ", args)
+
+		// ensure a runbook was provided
+		if len(args) != 1 {
+			log.Error("A runbook must be provided")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// the report format is taken from the extension of the report file
+		if report != "" {
+			if _, err := rpa.ReportFormat(report); err != nil {
+				log.Error(err)
+				cmd.Usage()
+				os.Exit(1)
+			}
+		}
+		a.Report = report
+		a.JUnit = junit
+
+		rb := rpa.LoadRunbook(args[0])
+
+		os.Exit(a.RunRunbook(rb))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	// command flags and configuration settings.
+	runCmd.PersistentFlags().String("report", "", "Write a report of the run before/after state & steps (report.html, report.md or report.json)")
+	runCmd.PersistentFlags().String("junit", "", "Write the results of the run as junit xml with a test case for each step of each group")
+}
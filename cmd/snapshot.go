@@ -0,0 +1,160 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the state of all copies & restore it later",
+	Long: `Save the state of all copies & restore it later
+
+examples:
+
+rpda snapshot save baseline.json
+
+rpda snapshot restore baseline.json --check
+
+rpda snapshot restore baseline.json
+
+	`,
+}
+
+// snapshotSaveCmd represents the snapshot save command
+var snapshotSaveCmd = &cobra.Command{
+	Use:   "save FILE",
+	Short: "Save the state of all copies to a file",
+	Long: `Save the state of all copies to a file
+
+The role, image access & transfer state of every copy of every consistency group
+is saved as json so that it can later be restored with 'rpda snapshot restore'
+or compared with 'rpda diff'.
+
+examples:
+
+rpda snapshot save baseline.json
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		log.Debug("snapshot save command args: ", args)
+
+		// ensure a file was provided
+		if len(args) != 1 {
+			log.Error("A snapshot file must be provided")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		if err := a.SaveSnapshot(args[0]); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotRestoreCmd represents the snapshot restore command
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore FILE",
+	Short: "Return all copies to the state saved in a file",
+	Long: `Return all copies to the state saved in a file
+
+The saved state is compared with the current state of each copy and the minimal
+set of enable & finish operations is applied. Copies in direct access which were
+replicating are finished and copies which were in direct access are enabled.
+Use --check to display the differences without making changes.
+
+examples:
+
+rpda snapshot restore baseline.json --check
+
+rpda snapshot restore baseline.json
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		log.Debug("snapshot restore command args: ", args)
+
+		// ensure a file was provided
+		if len(args) != 1 {
+			log.Error("A snapshot file must be provided")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		if err := a.RestoreSnapshot(args[0]); err != nil {
+			log.Error(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotSaveCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+
+	// command flags and configuration settings.
+	//
+}
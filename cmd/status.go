@@ -49,6 +49,10 @@ rpda status --all
 
 rpda status --group Example_CG
 
+rpda status --all --detail
+
+rpda status --app ERP
+
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 
@@ -73,8 +77,21 @@ rpda status --group Example_CG
 			log.Fatal(err)
 		}
 
+		app, err := cmd.Flags().GetString("app")
+		if err != nil {
+			log.Fatal(err)
+		}
+		detail, err := cmd.Flags().GetBool("detail")
+		if err != nil {
+			log.Fatal(err)
+		}
+
 		log.Debug("status command 'group' flag value: ", group)
 		log.Debug("status command 'all' flag value: ", all)
+		log.Debug("status command 'app' flag value: ", app)
+		log.Debug("status command 'detail' flag value: ", detail)
+
+		a.Detail = detail
 
 		if group != "" {
 			// display status of single group if a group name was provided
@@ -82,6 +99,9 @@ rpda status --group Example_CG
 		} else if all {
 			// display status of all groups if the all flag was provided
 			a.DisplayAllGroups()
+		} else if app != "" {
+			// display status of the groups of an application in dependency order
+			a.DisplayApp(app)
 		} else {
 			// otherwise, display command usage
 			cmd.Usage()
@@ -96,4 +116,6 @@ func init() {
 	// command flags and configuration settings.
 	statusCmd.PersistentFlags().Bool("all", false, "Display Status for All Consistency Groups")
 	statusCmd.PersistentFlags().String("group", "", "Display Status of Consistency Group by Name")
+	statusCmd.PersistentFlags().String("app", "", "Display Status of an Application (see 'applications' in config)")
+	statusCmd.PersistentFlags().Bool("detail", false, "Display Transfer, Image Access, Journal (incl. time until full) & RPO Details for Each Copy")
 }
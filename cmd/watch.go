@@ -0,0 +1,169 @@
+package cmd
+
+/*
+Copyright © 2020 Blayne Campbell
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice,
+   this list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its contributors
+   may be used to endorse or promote products derived from this software
+   without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+POSSIBILITY OF SUCH DAMAGE.
+*/
+
+import (
+	"os"
+	"time"
+
+	"github.com/bcambl/rpda/internal/pkg/rpa"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/cobra"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Continuously display Consistency Group Status",
+	Long: `Continuously display Consistency Group Status
+
+Copies which changed role, image access, storage access or transfer state since the
+previous refresh are highlighted. When --until is provided, the watch exits once all
+selected copies have a role, image access, storage access or transfer state matching
+the provided value.
+
+examples:
+
+rpda watch --all
+
+rpda watch --all --test --until LOGGED_ACCESS
+
+rpda watch --group Example_CG --dr --until DIRECT_ACCESS --interval 5s --timeout 10m
+
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		// Load API Configuration
+		c := &rpa.Config{}
+		c.Load()
+
+		// Load Consistency Group Name Identifiers
+		i := &rpa.Identifiers{}
+		i.Load()
+
+		a := &rpa.App{}
+		a.Config = c
+		a.Identifiers = i
+
+		group, err := cmd.Flags().GetString("group")
+		if err != nil {
+			log.Fatal(err)
+		}
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			log.Fatal(err)
+		}
+		copyByName, err := cmd.Flags().GetString("copy")
+		if err != nil {
+			log.Fatal(err)
+		}
+		testCopy, err := cmd.Flags().GetBool("test")
+		if err != nil {
+			log.Fatal(err)
+		}
+		drCopy, err := cmd.Flags().GetBool("dr")
+		if err != nil {
+			log.Fatal(err)
+		}
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			log.Fatal(err)
+		}
+		until, err := cmd.Flags().GetString("until")
+		if err != nil {
+			log.Fatal(err)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Debug("watch command 'group' flag value: ", group)
+		log.Debug("watch command 'all' flag value: ", all)
+		log.Debug("watch command 'copy' flag value: ", copyByName)
+		log.Debug("watch command 'test' flag value: ", testCopy)
+		log.Debug("watch command 'dr' flag value: ", drCopy)
+		log.Debug("watch command 'interval' flag value: ", interval)
+		log.Debug("watch command 'until' flag value: ", until)
+		log.Debug("watch command 'timeout' flag value: ", timeout)
+
+		// preflight checks
+
+		// ensure group or all flags were provided
+		if all == false && group == "" {
+			log.Error("Either --all or --group must be specified.")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// ensure only one copy selection was provided
+		if (copyByName != "" && (testCopy == true || drCopy == true)) || (testCopy == true && drCopy == true) {
+			log.Error("Only one of --test --dr or --copy can be specified")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		// ensure the api is not polled continuously
+		if interval <= 0 {
+			log.Error("--interval must be greater than 0")
+			cmd.Usage()
+			os.Exit(1)
+		}
+
+		a.Group = group
+		a.CopyName = copyByName
+
+		if drCopy == true {
+			a.CopyRegexp = a.Identifiers.CopyNodeRegexp
+		}
+		if testCopy == true {
+			a.CopyRegexp = a.Identifiers.TestNodeRegexp
+		}
+
+		a.Watch(interval, until, timeout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	// command flags and configuration settings.
+	watchCmd.PersistentFlags().Bool("all", false, "Watch All Consistency Groups")
+	watchCmd.PersistentFlags().String("group", "", "Watch Consistency Group by Name")
+	watchCmd.PersistentFlags().String("copy", "", "Only Watch Copies by Name")
+	watchCmd.PersistentFlags().Bool("test", false, "Only Watch Test Copies")
+	watchCmd.PersistentFlags().Bool("dr", false, "Only Watch DR Copies")
+	watchCmd.PersistentFlags().Duration("interval", 10*time.Second, "Time between refreshes")
+	watchCmd.PersistentFlags().String("until", "", "Exit once all watched copies reach a state (ie: LOGGED_ACCESS)")
+	watchCmd.PersistentFlags().Duration("timeout", 0, "Exit with an error if --until is not reached in time (ie: 30m)")
+}
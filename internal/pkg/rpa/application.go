@@ -0,0 +1,224 @@
+package rpa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// applicationTiers orders the groups of an application into tiers where each tier only depends on groups
+// in previous tiers. Groups within a tier do not depend on each other and may be operated in parallel.
+func (a *App) applicationTiers(appName string) ([][]string, error) {
+	groups, ok := a.Config.Applications[strings.ToLower(appName)]
+	if !ok || len(groups) == 0 {
+		return nil, fmt.Errorf("application '%s' not found in the 'applications' configuration", appName)
+	}
+
+	pending := make(map[string][]string) // group name -> unresolved dependencies
+	var order []string                   // config order of groups to keep tiers stable
+	for _, g := range groups {
+		if _, exists := pending[g.Group]; exists {
+			return nil, fmt.Errorf("application '%s': group '%s' is listed more than once", appName, g.Group)
+		}
+		pending[g.Group] = append([]string(nil), g.DependsOn...)
+		order = append(order, g.Group)
+	}
+	for _, g := range groups {
+		for _, dep := range g.DependsOn {
+			if _, exists := pending[dep]; !exists {
+				return nil, fmt.Errorf("application '%s': group '%s' depends on '%s' which is not part of the application",
+					appName, g.Group, dep)
+			}
+		}
+	}
+
+	var tiers [][]string
+	resolved := make(map[string]bool)
+	for len(resolved) < len(order) {
+		var tier []string
+		for _, name := range order {
+			if resolved[name] {
+				continue
+			}
+			ready := true
+			for _, dep := range pending[name] {
+				if !resolved[dep] {
+					ready = false
+				}
+			}
+			if ready {
+				tier = append(tier, name)
+			}
+		}
+		if len(tier) == 0 {
+			var cyclic []string
+			for _, name := range order {
+				if !resolved[name] {
+					cyclic = append(cyclic, name)
+				}
+			}
+			return nil, fmt.Errorf("application '%s': circular depends_on between groups: %s",
+				appName, strings.Join(cyclic, ", "))
+		}
+		for _, name := range tier {
+			resolved[name] = true
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// getApplicationTiers returns the tiers of an application, exiting when the configuration is invalid
+func (a *App) getApplicationTiers(appName string) [][]string {
+	tiers, err := a.applicationTiers(appName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return tiers
+}
+
+// getGroupIDsByName returns the ids of all consistency groups keyed by name
+func (a *App) getGroupIDsByName() (map[string]int, error) {
+	groups, err := a.getAllGroups()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]int)
+	for _, g := range groups {
+		name, err := a.getGroupName(g.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids[name] = g.ID
+	}
+	return ids, nil
+}
+
+// operateTier runs an operation on every group of a tier in parallel and returns the groups which failed
+func (a *App) operateTier(tier []string, ids map[string]int, operation func(int, string) error) []string {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for _, name := range tier {
+		groupID, ok := ids[name]
+		if !ok {
+			a.logger(name, "").Warnf("%s - Consistency group not found", name)
+			mu.Lock()
+			failed = append(failed, name)
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(groupID int, name string) {
+			defer wg.Done()
+			if err := operation(groupID, name); err != nil {
+				a.logger(name, "").Warnf("%s - %s\n", name, err)
+				mu.Lock()
+				failed = append(failed, name)
+				mu.Unlock()
+			}
+		}(groupID, name)
+	}
+	wg.Wait()
+	return failed
+}
+
+// tierOrder returns the indexes of tiers in the order they are operated on (last tier first when reverse)
+func tierOrder(tiers [][]string, reverse bool) []int {
+	order := make([]int, len(tiers))
+	for n := range tiers {
+		order[n] = n
+		if reverse {
+			order[n] = len(tiers) - 1 - n
+		}
+	}
+	return order
+}
+
+// tierGroups returns the groups of all tiers in the order they are operated on
+func tierGroups(tiers [][]string, reverse bool) []string {
+	var groups []string
+	for _, i := range tierOrder(tiers, reverse) {
+		groups = append(groups, tiers[i]...)
+	}
+	return groups
+}
+
+// operateApplication runs an operation tier by tier (last tier first when reverse is set),
+// stopping before the next tier when a group fails
+func (a *App) operateApplication(appName string, tiers [][]string, reverse bool, operation func(int, string) error) error {
+	start := time.Now()
+	ids, err := a.getGroupIDsByName()
+	if err != nil {
+		return err
+	}
+	order := tierOrder(tiers, reverse)
+	for n, i := range order {
+		fmt.Printf("%s - Tier %d: %s\n", appName, i+1, strings.Join(tiers[i], ", "))
+		failed := a.operateTier(tiers[i], ids, operation)
+		if len(failed) > 0 {
+			a.logger("", "").Errorf("%s - Tier %d failed for: %s", appName, i+1, strings.Join(failed, ", "))
+			if n < len(order)-1 {
+				a.logger("", "").Errorf("%s - Remaining tiers were skipped", appName)
+			}
+			return errors.New("application tier failed")
+		}
+		if n < len(order)-1 {
+			time.Sleep(time.Duration(a.Config.Delay) * time.Second)
+		}
+	}
+	elapsed := time.Since(start)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	return nil
+}
+
+// EnableApp wrapper for enabling Direct Image Access for the groups of an application in dependency order
+func (a *App) EnableApp(appName string) error {
+	tiers := a.getApplicationTiers(appName)
+	owner := a.beginRun("enable", tierGroups(tiers, false))
+	defer a.endRun(owner)
+	return a.operateApplication(appName, tiers, false, a.enableGroup)
+}
+
+// FinishApp wrapper for finishing Direct Image Access for the groups of an application in reverse dependency order
+func (a *App) FinishApp(appName string) error {
+	tiers := a.getApplicationTiers(appName)
+	owner := a.beginRun("finish", tierGroups(tiers, true))
+	defer a.endRun(owner)
+	return a.operateApplication(appName, tiers, true, a.finishGroup)
+}
+
+// DisplayApp displays the status of the groups of an application in dependency order
+func (a *App) DisplayApp(appName string) {
+	tiers := a.getApplicationTiers(appName)
+	ids, err := a.getGroupIDsByName()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var groupIDs []int
+	for _, name := range tierGroups(tiers, false) {
+		if id, ok := ids[name]; ok {
+			groupIDs = append(groupIDs, id)
+		}
+	}
+	journals, err := a.estimateJournals(groupIDs, a.Detail)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, tier := range tiers {
+		fmt.Printf("%s - Tier %d\n", appName, i+1)
+		for _, name := range tier {
+			fmt.Println(name) // consisntency group name
+			groupID, ok := ids[name]
+			if !ok {
+				fmt.Println("\tconsistency group not found")
+				continue
+			}
+			a.displayCopies(groupID, name, journals[groupID])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package rpa
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxAuditError is the maximum length of an api response body recorded as the error of an audit record
+const maxAuditError = 512
+
+// currentUser returns the name of the os user running rpda
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return os.Getenv("USER")
+	}
+	return u.Username
+}
+
+// auditPath returns the path of the audit log (default: $HOME/.rpda-audit.jsonl)
+func (a *App) auditPath() string {
+	if a.Config.AuditPath != "" {
+		return a.Config.AuditPath
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filepath.Join(home, ".rpda-audit.jsonl")
+}
+
+// requireTicket exits when 'audit.require_ticket' is set and a change is requested without --ticket
+func (a *App) requireTicket() {
+	if a.Config.RequireTicket && a.Config.Ticket == "" && !a.Config.CheckMode {
+		log.Fatal("A change ticket is required for this command, provide one with --ticket")
+	}
+}
+
+// writeAudit appends a record to the audit log
+func (a *App) writeAudit(r AuditRecord) {
+	data, err := json.Marshal(&r)
+	if err != nil {
+		logEntry(r.RunID, r.Group, r.Copy).Fatal(err)
+	}
+	f, err := os.OpenFile(a.auditPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logEntry(r.RunID, r.Group, r.Copy).Warnf("Unable to write audit log: %s", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		logEntry(r.RunID, r.Group, r.Copy).Warnf("Unable to write audit log: %s", err)
+	}
+}
+
+// newAuditRecord populates an audit record of an action on the copy of a task
+func (a *App) newAuditRecord(action string, t Task) AuditRecord {
+	host, _ := os.Hostname()
+	return AuditRecord{
+		Time:        time.Now(),
+		OSUser:      currentUser(),
+		RPAUser:     a.Config.Username,
+		Host:        host,
+		CommandLine: strings.Join(os.Args, " "),
+		Ticket:      a.Config.Ticket,
+		Reason:      a.Config.Reason,
+		Action:      action,
+		Group:       t.GroupName,
+		Copy:        t.CopyName,
+	}
+}
+
+// auditedRequest performs a mutating api request on the copy of a task and records it in the audit log.
+// Requests which fail or return a status other than 204 (No Content) are recorded as failures.
+func (a *App) auditedRequest(t Task, action, method, endpoint string, data io.Reader) ([]byte, int, error) {
+	r := a.newAuditRecord(action, t)
+	body, statusCode, err := a.apiRequest(method, endpoint, data)
+	r.Duration = time.Since(r.Time)
+	r.Endpoint = endpoint
+	r.Method = method
+	r.HTTPStatus = statusCode
+	r.Outcome = "success"
+	if err != nil {
+		r.Outcome = "failure"
+		r.Error = err.Error()
+	} else if statusCode != 204 {
+		r.Outcome = "failure"
+		r.Error = string(body)
+		if len(r.Error) > maxAuditError {
+			r.Error = r.Error[:maxAuditError]
+		}
+	}
+	a.runMu.Lock()
+	if a.run != nil {
+		r.RunID = a.run.ID
+		r.WindowOverride = a.groupRun(t.GroupName).WindowOverride
+	}
+	a.runMu.Unlock()
+	a.writeAudit(r)
+	return body, statusCode, err
+}
+
+// auditRun records a completed run, including every step performed on each group, in the audit log.
+// The caller must hold runMu.
+func (a *App) auditRun() {
+	if a.Config.CheckMode || a.run == nil {
+		return
+	}
+	r := a.newAuditRecord("run", Task{})
+	r.Time = a.run.Started
+	r.Duration = a.run.Finished.Sub(a.run.Started)
+	r.RunID = a.run.ID
+	r.Outcome = "success"
+	for _, g := range a.run.Groups {
+		if g.Status != groupDone {
+			r.Outcome = "failure"
+		}
+		r.WindowOverride = r.WindowOverride || g.WindowOverride
+	}
+	r.Run = a.run
+	a.writeAudit(r)
+}
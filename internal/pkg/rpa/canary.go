@@ -0,0 +1,89 @@
+package rpa
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// canaryReached reports whether the group at index i completes the canary groups of a bulk operation
+// which has further groups to operate on
+func (a *App) canaryReached(i, total int) bool {
+	return a.Canary > 0 && i == a.Canary-1 && a.Canary < total
+}
+
+// verifyGroup polls the requested copy of a group until it is in direct access (enable) or
+// image access is disabled (finish), returning an error when the poll limit is reached
+func (a *App) verifyGroup(groupID int, groupName string, enable bool) error {
+	for pollCount := 0; ; pollCount++ {
+		groupCopiesSettings, err := a.getGroupCopiesSettings(groupID)
+		if err != nil {
+			return err
+		}
+		copySettings, ok := a.findRequestedCopy(groupCopiesSettings)
+		if !ok {
+			return errors.New("requested copy not found")
+		}
+		enabled := copySettings.ImageAccessInformation.ImageAccessEnabled
+		if enable && enabled && copySettings.RoleInfo.Role == "ACTIVE" {
+			return nil
+		}
+		if !enable && !enabled {
+			return nil
+		}
+		if pollCount >= a.Config.PollMax {
+			return fmt.Errorf("copy %s is %s with image access enabled: %t", copySettings.Name, copySettings.RoleInfo.Role, enabled)
+		}
+		time.Sleep(time.Duration(a.Config.PollDelay) * time.Second)
+	}
+}
+
+// verifyCanary verifies the state of the canary groups, runs the canary hooks & asks the operator to
+// confirm (unless Yes is set) before the bulk operation continues with the remaining groups.
+// An error is returned when the operation must not continue.
+func (a *App) verifyCanary(enable bool, groups []GroupUID, groupNames []string, remaining int) error {
+	if a.Config.CheckMode {
+		fmt.Printf("Check mode enabled, skipping verification of %d canary groups\n", len(groups))
+		return nil
+	}
+	fmt.Printf("Verifying %d canary groups..\n", len(groups))
+	var failed []string
+	for i, g := range groups {
+		name := groupNames[i]
+		if err := a.verifyGroup(g.ID, name, enable); err != nil {
+			a.logger(name, "").Errorf("%s - Canary verification failed: %s", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		copySettings, err := a.getGroupRequestedCopy(g.ID)
+		if err != nil {
+			a.logger(name, "").Errorf("%s - %s", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		if err := a.runHooks("canary", newTask(name, copySettings, enable), nil); err != nil {
+			a.logger(name, "").Errorf("%s - %s", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		fmt.Printf("%s - Canary verified\n", name)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("canary verification failed for: %s", strings.Join(failed, ", "))
+	}
+
+	if a.Yes {
+		fmt.Printf("Continuing with the remaining %d consistency groups\n", remaining)
+		return nil
+	}
+	fmt.Printf("Continue with the remaining %d consistency groups? [y/N]: ", remaining)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	if err != nil || (answer != "y" && answer != "yes") {
+		return errors.New("stopped by operator after canary groups")
+	}
+	return nil
+}
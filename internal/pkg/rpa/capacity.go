@@ -0,0 +1,139 @@
+package rpa
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaults used when the 'journal_capacity' section is not configured
+const (
+	defaultJournalWarning        = 80.0 // percent
+	defaultJournalSampleInterval = 5 * time.Second
+)
+
+// journalEstimate is the journal usage of a copy along with its write rate & time until the journal is
+// full (estimated for copies in image access only)
+type journalEstimate struct {
+	Usage float64 // percent
+	Used  int64
+	Size  int64
+	Rate  float64       // bytes per second
+	Full  time.Duration // 0 when unknown
+}
+
+// journalEstimates holds the journal estimates of a consistency group by copy
+type journalEstimates map[GlobalCopyUID]journalEstimate
+
+// sampleJournals returns the current journal usage of each copy of a consistency group
+func (a *App) sampleJournals(groupID int) (journalEstimates, error) {
+	stats, err := a.getGroupStatistics(groupID)
+	if err != nil {
+		return nil, err
+	}
+	samples := make(journalEstimates)
+	for _, cs := range stats.ConsistencyGroupCopyStatistics {
+		js := cs.JournalStatistics
+		e := journalEstimate{Used: js.ActualJournalUsageInBytes, Size: js.JournalCapacityInBytes}
+		if e.Size > 0 {
+			e.Usage = float64(e.Used) / float64(e.Size) * 100
+		}
+		samples[cs.CopyUID.GlobalCopyUID] = e
+	}
+	return samples, nil
+}
+
+// estimateJournals samples the journal usage of the copies of each group. When sampleRate is set, copies
+// in image access are sampled a second time (once for all groups) after the sample interval to estimate
+// their write rate & the time until their journal is full.
+func (a *App) estimateJournals(groupIDs []int, sampleRate bool) (map[int]journalEstimates, error) {
+	estimates := make(map[int]journalEstimates)
+	accessed := make(map[int][]GlobalCopyUID)
+	for _, groupID := range groupIDs {
+		samples, err := a.sampleJournals(groupID)
+		if err != nil {
+			return nil, err
+		}
+		estimates[groupID] = samples
+		if !sampleRate {
+			continue
+		}
+		groupCopiesSettings, err := a.getGroupCopiesSettings(groupID)
+		if err != nil {
+			return nil, err
+		}
+		for _, cs := range groupCopiesSettings {
+			if cs.ImageAccessInformation.ImageAccessEnabled {
+				accessed[groupID] = append(accessed[groupID], cs.CopyUID.GlobalCopyUID)
+			}
+		}
+	}
+	interval := a.Config.JournalSampleInterval
+	if len(accessed) == 0 || interval <= 0 {
+		return estimates, nil
+	}
+
+	a.logger("", "").Debugf("Sampling journal usage of copies in image access again in %s", interval)
+	start := time.Now()
+	time.Sleep(interval)
+	elapsed := time.Since(start).Seconds()
+	for groupID, uids := range accessed {
+		second, err := a.sampleJournals(groupID)
+		if err != nil {
+			return nil, err
+		}
+		for _, uid := range uids {
+			first, ok := estimates[groupID][uid]
+			e, ok2 := second[uid]
+			if !ok || !ok2 {
+				continue
+			}
+			e.Rate = float64(e.Used-first.Used) / elapsed
+			if e.Rate > 0 && e.Size > e.Used {
+				e.Full = time.Duration(float64(e.Size-e.Used)/e.Rate) * time.Second
+			}
+			estimates[groupID][uid] = e
+		}
+	}
+	return estimates, nil
+}
+
+// journalWarning describes the journal usage of a copy when it is at or above the warning threshold
+func (a *App) journalWarning(e journalEstimate) string {
+	if e.Size == 0 || e.Usage < a.Config.JournalWarning {
+		return ""
+	}
+	return fmt.Sprintf("WARNING: journal %.1f%% used (%s of %s)", e.Usage, formatBytes(e.Used), formatBytes(e.Size))
+}
+
+// describeJournalFull describes the estimated time until the journal of a copy in image access is full
+func describeJournalFull(e journalEstimate) string {
+	if e.Rate <= 0 {
+		return "journal full in:   unknown (no journal growth measured)"
+	}
+	if e.Full == 0 {
+		return "journal full in:   now"
+	}
+	return fmt.Sprintf("journal full in:   ~%s (writing %s/s)", e.Full.Round(time.Minute), formatBytes(int64(e.Rate)))
+}
+
+// checkJournalCapacity warns when the journal of the copy to be enabled is highly utilized and refuses
+// the operation when MaxJournalUsage is reached. A single sample is taken, the time until the journal is
+// full is only estimated by 'status --detail' (the copy is not yet in image access).
+func (a *App) checkJournalCapacity(groupID int, t Task) error {
+	uid := GlobalCopyUID{CopyUID: t.CopyUID, ClusterUID: ClusterUID{ID: t.ClusterUID}}
+	samples, err := a.sampleJournals(groupID)
+	if err != nil {
+		return err
+	}
+	e, ok := samples[uid]
+	if !ok || e.Size == 0 {
+		return nil
+	}
+	if a.MaxJournalUsage > 0 && e.Usage >= a.MaxJournalUsage {
+		return fmt.Errorf("journal of copy %s is %.1f%% used (maximum: %.1f%%)", t.CopyName, e.Usage, a.MaxJournalUsage)
+	}
+	if w := a.journalWarning(e); w != "" {
+		a.logger(t.GroupName, t.CopyName).Warnf("%s - %s of copy %s", t.GroupName, w, t.CopyName)
+	}
+	return nil
+}
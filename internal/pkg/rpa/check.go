@@ -0,0 +1,156 @@
+package rpa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Nagios/Icinga plugin exit codes
+const (
+	CheckOK       = 0
+	CheckWarning  = 1
+	CheckCritical = 2
+	CheckUnknown  = 3
+)
+
+var checkStateNames = map[int]string{
+	CheckOK:       "OK",
+	CheckWarning:  "WARNING",
+	CheckCritical: "CRITICAL",
+	CheckUnknown:  "UNKNOWN",
+}
+
+// rpoResult holds the outcome of comparing a single copy lag against its threshold
+type rpoResult struct {
+	Label    string
+	Lag      time.Duration
+	Warning  time.Duration
+	Critical time.Duration
+	State    int
+}
+
+// rpoThresholds determines the warning & critical thresholds for a copy.
+// A per-group threshold takes precedence over the global threshold, which takes precedence over the
+// rpo configured on the link of the copy. The warning threshold defaults to 80% of the critical threshold.
+func (a *App) rpoThresholds(d CopyDetail) (time.Duration, time.Duration) {
+	critical := d.ConfiguredRPO
+	if a.Config.RPOMax > 0 {
+		critical = a.Config.RPOMax
+	}
+	if t, ok := a.Config.RPOGroups[strings.ToLower(d.GroupName)]; ok {
+		critical = t
+	}
+	warning := critical * 8 / 10
+	if a.Config.RPOWarning > 0 && a.Config.RPOWarning < critical {
+		warning = a.Config.RPOWarning
+	}
+	return warning, critical
+}
+
+// CheckRPO compares the current lag of each replica copy against the rpo thresholds, prints the result
+// in the Nagios/Icinga plugin format (including perfdata) and returns the plugin exit code.
+// When App.Group is empty, all consistency groups are checked.
+func (a *App) CheckRPO() int {
+	results, err := a.collectRPOResults()
+	if err != nil {
+		fmt.Printf("RPO UNKNOWN - %s\n", err)
+		return CheckUnknown
+	}
+	if len(results) == 0 {
+		if a.Group != "" {
+			fmt.Printf("RPO UNKNOWN - no replica copies found for consistency group %s\n", a.Group)
+		} else {
+			fmt.Println("RPO UNKNOWN - no replica copies found")
+		}
+		return CheckUnknown
+	}
+
+	state := CheckOK
+	var problems, perfdata []string
+	for _, r := range results {
+		if r.State > state {
+			state = r.State
+		}
+		if r.State != CheckOK {
+			problems = append(problems, fmt.Sprintf("%s lag %s exceeds %s", r.Label, r.Lag, thresholdFor(r)))
+		}
+		perfdata = append(perfdata, fmt.Sprintf("'%s'=%.0fs;%.0f;%.0f;0;",
+			r.Label, r.Lag.Seconds(), r.Warning.Seconds(), r.Critical.Seconds()))
+	}
+
+	summary := fmt.Sprintf("%d copies within RPO", len(results))
+	if len(problems) > 0 {
+		summary = strings.Join(problems, ", ")
+	}
+	fmt.Printf("RPO %s - %s | %s\n", checkStateNames[state], summary, strings.Join(perfdata, " "))
+	// long output (one line per copy) for the plugin detail view
+	for _, r := range results {
+		fmt.Printf("%s: %s lag %s (warning %s, critical %s)\n",
+			checkStateNames[r.State], r.Label, r.Lag, r.Warning, r.Critical)
+	}
+	return state
+}
+
+func thresholdFor(r rpoResult) time.Duration {
+	if r.State == CheckCritical {
+		return r.Critical
+	}
+	return r.Warning
+}
+
+func (a *App) collectRPOResults() ([]rpoResult, error) {
+	groups := make(map[int]string)
+	if a.Group != "" {
+		groupID, err := a.getGroupIDByName(a.Group)
+		if err != nil {
+			return nil, err
+		}
+		groups[groupID] = a.Group
+	} else {
+		allGroups, err := a.getAllGroups()
+		if err != nil {
+			return nil, err
+		}
+		groupNames, err := a.getGroupNames(allGroups)
+		if err != nil {
+			return nil, err
+		}
+		for i, g := range allGroups {
+			groups[g.ID] = groupNames[i]
+		}
+	}
+
+	var results []rpoResult
+	for groupID, groupName := range groups {
+		details, err := a.getGroupCopyDetails(groupID, groupName)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range details {
+			// the production copy has no lag of its own
+			if a.Identifiers.ProductionNodeRegexp.MatchString(d.Name) {
+				continue
+			}
+			r := rpoResult{
+				Label: groupName + "/" + d.Name,
+				Lag:   d.CurrentRPO,
+			}
+			r.Warning, r.Critical = a.rpoThresholds(d)
+			if r.Critical == 0 {
+				a.logger(groupName, d.Name).Debugf("%s - no rpo threshold available, skipping", r.Label)
+				continue
+			}
+			switch {
+			case r.Lag > r.Critical:
+				r.State = CheckCritical
+			case r.Lag > r.Warning:
+				r.State = CheckWarning
+			}
+			results = append(results, r)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Label < results[j].Label })
+	return results, nil
+}
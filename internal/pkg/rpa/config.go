@@ -2,6 +2,8 @@ package rpa
 
 import (
 	"regexp"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -17,6 +19,73 @@ func (c *Config) Load() *Config {
 	c.PollMax = viper.GetInt("api.pollmax")
 	c.CheckMode = viper.GetBool("check")
 	c.Debug = viper.GetBool("debug")
+	c.RPOMax = viper.GetDuration("rpo.max")
+	c.RPOWarning = viper.GetDuration("rpo.warning")
+	c.ExporterListen = viper.GetString("exporter.listen")
+	c.ExporterInterval = viper.GetDuration("exporter.interval")
+	// viper keys are case insensitive, application names are matched in lower case
+	if err := viper.UnmarshalKey("applications", &c.Applications); err != nil {
+		log.Fatalf("Invalid applications configuration: %s", err)
+	}
+	if err := viper.UnmarshalKey("hooks", &c.Hooks); err != nil {
+		log.Fatalf("Invalid hooks configuration: %s", err)
+	}
+	for i, g := range c.Hooks.Groups {
+		pattern, err := regexp.Compile(g.Regexp)
+		if err != nil {
+			log.Fatalf("Invalid hooks regexp '%s': %s", g.Regexp, err)
+		}
+		c.Hooks.Groups[i].Pattern = pattern
+	}
+	if err := viper.UnmarshalKey("windows", &c.Windows); err != nil {
+		log.Fatalf("Invalid windows configuration: %s", err)
+	}
+	for i := range c.Windows {
+		if err := c.Windows[i].compile(); err != nil {
+			log.Fatalf("Invalid maintenance window %d: %s", i+1, err)
+		}
+	}
+	if err := viper.UnmarshalKey("webhooks", &c.Webhooks); err != nil {
+		log.Fatalf("Invalid webhooks configuration: %s", err)
+	}
+	for i := range c.Webhooks {
+		if err := c.Webhooks[i].compile(); err != nil {
+			log.Fatalf("Invalid webhook %d: %s", i+1, err)
+		}
+	}
+	if err := viper.UnmarshalKey("email", &c.Email); err != nil {
+		log.Fatalf("Invalid email configuration: %s", err)
+	}
+	if c.Email.Host != "" {
+		if err := c.Email.validate(); err != nil {
+			log.Fatalf("Invalid email configuration: %s", err)
+		}
+	}
+	c.OverrideWindow = viper.GetBool("override_window")
+	c.AuditPath = viper.GetString("audit.path")
+	c.Ticket = viper.GetString("ticket")
+	c.RequireTicket = viper.GetBool("audit.require_ticket")
+	c.Reason = viper.GetString("reason")
+	c.JournalPath = viper.GetString("journal.path")
+	c.JournalWarning = defaultJournalWarning
+	if viper.IsSet("journal_capacity.warning") {
+		c.JournalWarning = viper.GetFloat64("journal_capacity.warning")
+	}
+	c.JournalSampleInterval = defaultJournalSampleInterval
+	if viper.IsSet("journal_capacity.sample_interval") {
+		c.JournalSampleInterval = viper.GetDuration("journal_capacity.sample_interval")
+	}
+	c.LeasesPath = viper.GetString("leases.path")
+	c.LeaseWarning = viper.GetDuration("leases.warning")
+	c.RPOGroups = make(map[string]time.Duration)
+	for group, threshold := range viper.GetStringMapString("rpo.groups") {
+		d, err := time.ParseDuration(threshold)
+		if err != nil {
+			logEntry("", group, "").Fatalf("Invalid rpo threshold for group %s: %s", group, err)
+		}
+		// viper keys are case insensitive, group names are matched in lower case
+		c.RPOGroups[strings.ToLower(group)] = d
+	}
 
 	log.WithFields(log.Fields{
 		"RPAURL":    c.RPAURL,
@@ -27,6 +96,8 @@ func (c *Config) Load() *Config {
 		"PollMax":   c.PollMax,
 		"CheckMode": c.CheckMode,
 		"Debug":     c.Debug,
+		"RPOMax":    c.RPOMax,
+		"RPOGroups": c.RPOGroups,
 	}).Debug("Config struct variable assignments")
 
 	return c
@@ -0,0 +1,124 @@
+package rpa
+
+import (
+	"fmt"
+	"os"
+)
+
+// groupSnapshotByName returns the group of a snapshot by name, or nil when not found
+func groupSnapshotByName(s StatusSnapshot, name string) *GroupSnapshot {
+	for i := range s.Groups {
+		if s.Groups[i].Name == name {
+			return &s.Groups[i]
+		}
+	}
+	return nil
+}
+
+// copySnapshotByName returns the copy of a group snapshot by name, or nil when not found
+func copySnapshotByName(g *GroupSnapshot, name string) *CopySnapshot {
+	for i := range g.Copies {
+		if g.Copies[i].Name == name {
+			return &g.Copies[i]
+		}
+	}
+	return nil
+}
+
+// diffSnapshots describes the groups & copies added or removed and the role, image access & transfer
+// state changes between two snapshots
+func diffSnapshots(before, after StatusSnapshot) []string {
+	var changes []string
+	for _, bg := range before.Groups {
+		ag := groupSnapshotByName(after, bg.Name)
+		if ag == nil {
+			changes = append(changes, fmt.Sprintf("- %s: consistency group removed", bg.Name))
+			continue
+		}
+		for _, bc := range bg.Copies {
+			ac := copySnapshotByName(ag, bc.Name)
+			if ac == nil {
+				changes = append(changes, fmt.Sprintf("- %s - %s: copy removed", bg.Name, bc.Name))
+				continue
+			}
+			changes = append(changes, diffCopy(bg.Name, bc, *ac)...)
+		}
+		for _, ac := range ag.Copies {
+			if copySnapshotByName(&bg, ac.Name) == nil {
+				changes = append(changes, fmt.Sprintf("+ %s - %s: copy added (%s)", bg.Name, ac.Name, ac.Role))
+			}
+		}
+	}
+	for _, ag := range after.Groups {
+		if groupSnapshotByName(before, ag.Name) == nil {
+			changes = append(changes, fmt.Sprintf("+ %s: consistency group added (%d copies)", ag.Name, len(ag.Copies)))
+		}
+	}
+	return changes
+}
+
+// diffCopy describes the changes to a single copy between two snapshots
+func diffCopy(groupName string, before, after CopySnapshot) []string {
+	var changes []string
+	change := func(field, b, a string) {
+		if b != a {
+			changes = append(changes, fmt.Sprintf("~ %s - %s: %s %s -> %s", groupName, before.Name, field, displayState(b), displayState(a)))
+		}
+	}
+	change("role", before.Role, after.Role)
+	change("image access", imageAccessState(before), imageAccessState(after))
+	change("storage access", before.StorageAccessState, after.StorageAccessState)
+	change("transfer state", before.TransferState, after.TransferState)
+	return changes
+}
+
+// imageAccessState describes the image access of a copy (ie: disabled or enabled (LOGGED_ACCESS))
+func imageAccessState(c CopySnapshot) string {
+	if !c.ImageAccessEnabled {
+		return "disabled"
+	}
+	if c.ImageAccessMode == "" {
+		return "enabled"
+	}
+	return fmt.Sprintf("enabled (%s)", c.ImageAccessMode)
+}
+
+// Diff compares a saved snapshot with a second saved snapshot (or the live state when afterPath is empty)
+// and displays the differences. Following diff(1), 0 is returned when there are no differences, 1 when
+// there are differences & 2 when a snapshot could not be read.
+func (a *App) Diff(beforePath, afterPath string) int {
+	before, err := LoadSnapshot(beforePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var after StatusSnapshot
+	afterName := "live"
+	if afterPath == "" {
+		after, err = a.takeSnapshot()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	} else {
+		after, err = LoadSnapshot(afterPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		afterName = afterPath
+	}
+
+	layout := "2006-01-02 15:04:05"
+	fmt.Printf("before: %s (%s)\n", beforePath, before.Taken.Format(layout))
+	fmt.Printf("after:  %s (%s)\n", afterName, after.Taken.Format(layout))
+	changes := diffSnapshots(before, after)
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+		return 0
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	return 1
+}
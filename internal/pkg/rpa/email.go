@@ -0,0 +1,275 @@
+package rpa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaultEmailPort is used when 'email.port' is not configured
+const defaultEmailPort = 25
+
+// defaultEmailCommands are reported when 'email.commands' is not configured
+var defaultEmailCommands = []string{"enable", "finish"}
+
+// emailText is the plain text version of the report of a run
+var emailText = template.Must(template.New("text").Funcs(reportFuncs).Parse(`rpda {{.Command}} run {{.RunID}}: {{.Summary.Outcome}}{{if .Summary.Aborted}} (aborted by a fatal error){{end}}{{if .CheckMode}} (check mode){{end}}
+
+Operator:  {{.Operator}}@{{.Host}}
+{{- if .Ticket}}
+Ticket:    {{.Ticket}}{{end}}
+{{- if .Reason}}
+Reason:    {{.Reason}}{{end}}
+Started:   {{time .Summary.Started}}
+Duration:  {{duration .Summary.Duration}}
+Groups:    {{.Summary.Succeeded}} succeeded, {{.Summary.Failed}} failed, {{.Summary.Pending}} not reached
+{{range .Summary.Groups}}
+{{.Name}} ({{orDash .Copy}}): {{.Status}} in {{duration (groupDuration .)}}
+{{- if .Error}}
+  FAILED: {{.Error}}{{end}}
+{{- range .Steps}}
+  {{printf "%-28s" .Name}} {{printf "%10s" (duration .Duration)}}  {{if .Error}}failed: {{.Error}}{{else if not .Completed}}interrupted{{else}}ok{{end}}
+{{- end}}
+{{end}}`))
+
+// emailHTML is the html version of the report of a run
+var emailHTML = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; font-size: 14px;">
+<h2>rpda {{.Command}} run {{.RunID}}:
+{{if eq .Summary.Outcome "success"}}<span style="color: #2e7d32;">success</span>{{else}}<span style="color: #c62828;">failure</span>{{end}}
+{{if .Summary.Aborted}}(aborted by a fatal error){{end}}
+{{if .CheckMode}}(check mode){{end}}</h2>
+<table cellpadding="4">
+<tr><td><b>Operator</b></td><td>{{.Operator}}@{{.Host}}</td></tr>
+{{if .Ticket}}<tr><td><b>Ticket</b></td><td>{{.Ticket}}</td></tr>{{end}}
+{{if .Reason}}<tr><td><b>Reason</b></td><td>{{.Reason}}</td></tr>{{end}}
+<tr><td><b>Started</b></td><td>{{time .Summary.Started}}</td></tr>
+<tr><td><b>Duration</b></td><td>{{duration .Summary.Duration}}</td></tr>
+<tr><td><b>Groups</b></td><td>{{.Summary.Succeeded}} succeeded, {{.Summary.Failed}} failed, {{.Summary.Pending}} not reached</td></tr>
+</table>
+<h3>Consistency Groups</h3>
+<table border="1" cellpadding="4" style="border-collapse: collapse;">
+<tr><th>Group</th><th>Copy</th><th>Status</th><th>Duration</th><th>Error</th></tr>
+{{range .Summary.Groups}}<tr>
+<td>{{.Name}}</td><td>{{orDash .Copy}}</td>
+<td{{if eq .Status "failed"}} style="color: #c62828;"{{end}}>{{.Status}}</td>
+<td>{{duration (groupDuration .)}}</td><td>{{.Error}}</td>
+</tr>
+{{end}}</table>
+<h3>Steps</h3>
+{{range .Summary.Groups}}{{if .Steps}}<p><b>{{.Name}}</b></p>
+<table border="1" cellpadding="4" style="border-collapse: collapse;">
+<tr><th>Step</th><th>Started</th><th>Duration</th><th>Result</th></tr>
+{{range .Steps}}<tr><td>{{.Name}}</td><td>{{time .Started}}</td><td>{{duration .Duration}}</td>
+<td>{{if .Error}}<span style="color: #c62828;">failed: {{.Error}}</span>{{else if not .Completed}}<span style="color: #c62828;">interrupted</span>{{else}}ok{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// groupDuration returns the time from the start of the first step to the end of the last step of a group
+func groupDuration(g *GroupRun) time.Duration {
+	if len(g.Steps) == 0 {
+		return 0
+	}
+	first, last := g.Steps[0], g.Steps[len(g.Steps)-1]
+	return last.Started.Add(last.Duration).Sub(first.Started)
+}
+
+// envelopeAddress returns the address of a header address (ie: rpda@example.com for RPDA <rpda@example.com>)
+func envelopeAddress(s string) string {
+	if a, err := mail.ParseAddress(s); err == nil {
+		return a.Address
+	}
+	return s
+}
+
+// validate checks that the email configuration can be used to send reports
+func (m *Email) validate() error {
+	if m.From == "" {
+		return errors.New("a from address is required")
+	}
+	if len(m.To) == 0 {
+		return errors.New("at least one recipient is required")
+	}
+	if m.Password != "" && m.Username == "" {
+		return errors.New("a username is required with a password")
+	}
+	return nil
+}
+
+// reports reports whether the runs of a command are reported
+func (m *Email) reports(command string) bool {
+	commands := m.Commands
+	if len(commands) == 0 {
+		commands = defaultEmailCommands
+	}
+	for _, c := range commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the email notifier
+func (m *Email) String() string {
+	return "email via " + m.Host
+}
+
+// Notify sends the report of a run once it has finished
+func (m *Email) Notify(e Event) error {
+	if e.Event != "run_finished" || e.Summary == nil || !m.reports(e.Command) {
+		return nil
+	}
+	msg, err := m.message(e)
+	if err != nil {
+		return fmt.Errorf("email report: %s", err)
+	}
+	if err := m.send(msg); err != nil {
+		return fmt.Errorf("email report via %s: %s", m.Host, err)
+	}
+	return nil
+}
+
+// subject returns the subject of the report of a run (ie: [rpda] enable failed: 1 of 3 groups failed)
+func (m *Email) subject(e Event) string {
+	s := e.Summary
+	total := len(s.Groups)
+	subject := fmt.Sprintf("[rpda] %s succeeded: %d of %d groups", e.Command, s.Succeeded, total)
+	if s.Outcome != "success" {
+		result := "failed"
+		if s.Aborted {
+			result = "aborted"
+		}
+		subject = fmt.Sprintf("[rpda] %s %s: %d of %d groups failed", e.Command, result, s.Failed, total)
+		if s.Pending > 0 {
+			subject += fmt.Sprintf(", %d not reached", s.Pending)
+		}
+	}
+	if e.Ticket != "" {
+		subject += " (" + e.Ticket + ")"
+	}
+	if e.CheckMode {
+		subject += " [check mode]"
+	}
+	return subject
+}
+
+// message renders the report of a run as a multipart/alternative message with plain text & html parts
+func (m *Email) message(e Event) ([]byte, error) {
+	var text, html bytes.Buffer
+	if err := emailText.Execute(&text, e); err != nil {
+		return nil, err
+	}
+	if err := emailHTML.Execute(&html, e); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", part.contentType)
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		pw, err := w.CreatePart(h)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		qp.Close()
+	}
+	w.Close()
+
+	id := make([]byte, 12)
+	rand.Read(id)
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		fmt.Fprintf(&msg, "Cc: %s\r\n", strings.Join(m.Cc, ", "))
+	}
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.subject(e)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s.%s@%s>\r\n", e.RunID, hex.EncodeToString(id), e.Host)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// send delivers a message to the recipients, using STARTTLS & authentication when configured
+func (m *Email) send(msg []byte) error {
+	port := m.Port
+	if port == 0 {
+		port = defaultEmailPort
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(m.Host, strconv.Itoa(port)), 30*time.Second)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if m.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection to a remote host
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(envelopeAddress(m.From)); err != nil {
+		return err
+	}
+	for _, rcpt := range append(append([]string(nil), m.To...), m.Cc...) {
+		if err := c.Rcpt(envelopeAddress(rcpt)); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
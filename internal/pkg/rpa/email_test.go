@@ -0,0 +1,257 @@
+package rpa
+
+import (
+	"bufio"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSink is a minimal smtp server accepting a single session & recording the envelope & message
+type smtpSink struct {
+	ln       net.Listener
+	commands []string
+	rcpts    []string
+	data     string
+	done     chan struct{}
+}
+
+// newSMTPSink starts an smtp sink on a random local port
+func newSMTPSink(t *testing.T) *smtpSink {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpSink{ln: ln, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+// port returns the port the sink is listening on
+func (s *smtpSink) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+// wait waits for the session to end & stops the sink
+func (s *smtpSink) wait(t *testing.T) {
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the smtp session to end")
+	}
+	s.ln.Close()
+}
+
+func (s *smtpSink) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		for _, l := range lines {
+			conn.Write([]byte(l + "\r\n"))
+		}
+	}
+	reply("220 localhost ESMTP sink")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		s.commands = append(s.commands, verb)
+		switch verb {
+		case "EHLO", "HELO":
+			// STARTTLS is not advertised
+			reply("250-localhost", "250 8BITMIME")
+		case "RCPT":
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			reply("250 ok")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = b.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// testEvent returns the run_finished event of an enable run with a failed group
+func testEvent() Event {
+	started := time.Date(2020, 4, 20, 22, 0, 0, 0, time.UTC)
+	return Event{
+		Event:    "run_finished",
+		Time:     started.Add(time.Minute),
+		RunID:    "20200420-220000-80dd",
+		Command:  "enable",
+		Operator: "jdoe",
+		Host:     "ops01",
+		Ticket:   "CHG0042",
+		Summary: &RunSummary{
+			Outcome:   "failure",
+			Started:   started,
+			Duration:  time.Minute,
+			Succeeded: 1,
+			Failed:    1,
+			Groups: []*GroupRun{
+				{Name: "ERP_DB_CG", Copy: "TC_ERP_DB_CN", Status: groupDone,
+					Steps: []*Step{{Name: "image_access", Started: started, Duration: time.Second}}},
+				{Name: "ERP_APP_CG", Status: groupFailed, Error: "pre_enable hook failed"},
+			},
+		},
+	}
+}
+
+func TestEmailNotify(t *testing.T) {
+	sink := newSMTPSink(t)
+	m := &Email{
+		Host: "127.0.0.1",
+		Port: sink.port(),
+		From: "RPDA <rpda@example.com>",
+		To:   []string{"dr-team@example.com"},
+		Cc:   []string{"IT Management <it-management@example.com>"},
+	}
+	if err := m.Notify(testEvent()); err != nil {
+		t.Fatal(err)
+	}
+	sink.wait(t)
+
+	wantRcpts := []string{"dr-team@example.com", "it-management@example.com"}
+	if strings.Join(sink.rcpts, ",") != strings.Join(wantRcpts, ",") {
+		t.Errorf("RCPT = %v, want %v", sink.rcpts, wantRcpts)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(sink.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[rpda] enable failed: 1 of 2 groups failed (CHG0042)"; subject != want {
+		t.Errorf("Subject = %q, want %q", subject, want)
+	}
+	if cc := msg.Header.Get("Cc"); !strings.Contains(cc, "it-management@example.com") {
+		t.Errorf("Cc = %q, want it-management@example.com", cc)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s, want multipart/alternative", mediaType)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{"text/plain", "text/html"} {
+		part, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("reading %s part: %s", want, err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partType != want {
+			t.Errorf("part Content-Type = %s, want %s", partType, want)
+		}
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{"20200420-220000-80dd", "ERP_DB_CG", "pre_enable hook failed"} {
+			if !strings.Contains(string(body), s) {
+				t.Errorf("%s part does not contain %q", want, s)
+			}
+		}
+	}
+	if _, err := r.NextPart(); err == nil {
+		t.Error("unexpected third part")
+	}
+}
+
+func TestEmailNotifyAborted(t *testing.T) {
+	sink := newSMTPSink(t)
+	m := &Email{Host: "127.0.0.1", Port: sink.port(), From: "rpda@example.com", To: []string{"dr-team@example.com"}}
+	e := testEvent()
+	e.Summary.Aborted = true
+	e.Summary.Groups = append(e.Summary.Groups, &GroupRun{Name: "WEB_CG", Status: groupPending})
+	e.Summary.Pending = 1
+	if err := m.Notify(e); err != nil {
+		t.Fatal(err)
+	}
+	sink.wait(t)
+
+	msg, err := mail.ReadMessage(strings.NewReader(sink.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[rpda] enable aborted: 1 of 3 groups failed, 1 not reached (CHG0042)"; subject != want {
+		t.Errorf("Subject = %q, want %q", subject, want)
+	}
+	if !strings.Contains(sink.data, "aborted by a fatal error") {
+		t.Error("message does not mention the run was aborted")
+	}
+}
+
+func TestEmailStartTLSRequired(t *testing.T) {
+	sink := newSMTPSink(t)
+	m := &Email{
+		Host:     "127.0.0.1",
+		Port:     sink.port(),
+		StartTLS: true,
+		From:     "rpda@example.com",
+		To:       []string{"dr-team@example.com"},
+	}
+	err := m.Notify(testEvent())
+	if err == nil || !strings.Contains(err.Error(), "server does not support STARTTLS") {
+		t.Fatalf("Notify() error = %v, want server does not support STARTTLS", err)
+	}
+	sink.wait(t)
+	for _, c := range sink.commands {
+		if c == "MAIL" || c == "RCPT" || c == "DATA" {
+			t.Errorf("%s sent without STARTTLS", c)
+		}
+	}
+}
+
+func TestEmailNotifyCommands(t *testing.T) {
+	m := &Email{Host: "127.0.0.1", Port: 1, From: "rpda@example.com", To: []string{"dr-team@example.com"}}
+	e := testEvent()
+	e.Command = "run" // not reported by default, nothing is sent
+	if err := m.Notify(e); err != nil {
+		t.Errorf("Notify() error = %v, want nil", err)
+	}
+	e = testEvent()
+	e.Event = "group_failed"
+	if err := m.Notify(e); err != nil {
+		t.Errorf("Notify() error = %v, want nil", err)
+	}
+}
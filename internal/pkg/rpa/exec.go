@@ -0,0 +1,140 @@
+package rpa
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Exec enables direct access for the requested copy of App.Group, runs the provided command with
+// environment variables describing the copy & image, then always finishes direct access (including when
+// the command fails or the application is interrupted). The exit status of the command is returned.
+// Copies which were already in direct access are neither used nor finished.
+func (a *App) Exec(command []string) int {
+	// capture interrupts for the lifetime of the run so that finish is always performed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	groupID, err := a.getGroupIDByName(a.Group)
+	if err != nil {
+		a.logger(a.Group, "").Error(err)
+		return 1
+	}
+	copySettings, err := a.getGroupRequestedCopy(groupID)
+	if err != nil {
+		a.logger(a.Group, "").Errorf("%s - %s", a.Group, err)
+		return 1
+	}
+	t := newTask(a.Group, copySettings, true)
+
+	if a.Config.CheckMode {
+		fmt.Printf("%s - Would run command with direct access enabled for copy %s: %s\n",
+			a.Group, t.CopyName, strings.Join(command, " "))
+		return 0
+	}
+
+	owner := a.beginRun("exec", []string{a.Group})
+	defer a.endRun(owner)
+
+	status := 0
+	err = a.EnableOne()
+	changed := a.changedByRun(a.Group)
+	switch {
+	case err != nil:
+		a.logger(a.Group, "").Errorf("%s - Unable to enable direct access, command will not be run", a.Group)
+		status = 1
+	case !changed:
+		// the copy was already in direct access (ie: enabled by someone else), it is not ours to use or finish
+		a.logger(a.Group, t.CopyName).Errorf("%s - Copy %s was already in direct access before this run, command will not be run", a.Group, t.CopyName)
+		return 1
+	}
+
+	select {
+	case sig := <-signals:
+		a.logger(a.Group, "").Warnf("%s - Received %s, command will not be run", a.Group, sig)
+		status = 1
+	default:
+	}
+
+	if status == 0 {
+		status = a.runCommand(context.Background(), a.Group, command, append(os.Environ(), a.execEnv(groupID, t)...), signals)
+	}
+
+	// only finish a copy which was changed by this run
+	if !changed {
+		return status
+	}
+
+	// always return the copy to replication, regardless of the command result
+	err = a.FinishOne()
+	if err != nil {
+		a.logger(a.Group, t.CopyName).Errorf("%s - Unable to finish direct access for copy %s", a.Group, t.CopyName)
+		if status == 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+// execEnv returns the environment variables for a command describing the group, copy & accessed image
+func (a *App) execEnv(groupID int, t Task) []string {
+	env := []string{
+		"RPDA_GROUP=" + t.GroupName,
+		"RPDA_GROUP_UID=" + strconv.Itoa(t.GroupUID),
+		"RPDA_CLUSTER_UID=" + strconv.Itoa(t.ClusterUID),
+		"RPDA_COPY=" + t.CopyName,
+		"RPDA_COPY_UID=" + strconv.Itoa(t.CopyUID),
+	}
+	details, err := a.getGroupCopyDetails(groupID, t.GroupName)
+	if err != nil {
+		a.logger(t.GroupName, t.CopyName).Warnf("%s - Unable to determine the accessed image: %s", t.GroupName, err)
+	}
+	for _, d := range details {
+		if d.Name == t.CopyName && !d.ImageTimestamp.IsZero() {
+			env = append(env, "RPDA_IMAGE_TIMESTAMP="+d.ImageTimestamp.Format(time.RFC3339))
+		}
+	}
+	return env
+}
+
+// runCommand runs a command, forwarding any received signals, and returns its exit status.
+// The command is killed if the context is done before it exits.
+func (a *App) runCommand(ctx context.Context, label string, command []string, env []string, signals chan os.Signal) int {
+	fmt.Printf("%s - Running command: %s\n", label, strings.Join(command, " "))
+	c := exec.CommandContext(ctx, command[0], command[1:]...)
+	c.Env = env
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Start(); err != nil {
+		a.logger(a.Group, "").Errorf("%s - %s", label, err)
+		return 127
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.Wait() }()
+	for {
+		select {
+		case sig := <-signals:
+			a.logger(a.Group, "").Warnf("%s - Received %s, waiting for command to exit before finishing", label, sig)
+			c.Process.Signal(sig)
+		case err := <-done:
+			status := c.ProcessState.ExitCode()
+			if ws, ok := c.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				// follow the shell convention for commands terminated by a signal
+				status = 128 + int(ws.Signal())
+			} else if err != nil && status <= 0 {
+				status = 1
+			}
+			fmt.Printf("%s - Command exited with status %d\n", label, status)
+			return status
+		}
+	}
+}
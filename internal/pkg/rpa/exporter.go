@@ -0,0 +1,177 @@
+package rpa
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exporter caches the most recent collection of copy details for the metrics endpoint
+type exporter struct {
+	mu             sync.Mutex
+	copies         []CopyDetail
+	groups         int
+	scrapeErrors   int
+	scrapeSuccess  bool
+	scrapeDuration time.Duration
+	lastScrape     time.Time
+}
+
+// ServeMetrics collects the state of all groups & copies every interval and serves the results
+// as prometheus metrics on the listen address (ie: ':9650') until the application is terminated.
+func (a *App) ServeMetrics(listen string, interval time.Duration) {
+	e := &exporter{}
+
+	// listen before collection starts so a bind failure exits before any collection is in flight
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		for {
+			a.collectMetrics(e)
+			time.Sleep(interval)
+		}
+	}()
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(e.render())
+	})
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html><head><title>rpda exporter</title></head><body>`+
+			`<h1>rpda exporter</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+	})
+
+	fmt.Printf("Serving metrics on %s/metrics (collecting every %s)\n", listen, interval)
+	log.Fatal(http.Serve(ln, nil))
+}
+
+// collectMetrics refreshes the exporter cache. API failures are counted as scrape errors and the
+// previously collected copies are kept so that series do not disappear during a transient outage.
+func (a *App) collectMetrics(e *exporter) {
+	start := time.Now()
+	copies, groups, err := a.collectCopyDetails()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scrapeDuration = time.Since(start)
+	e.lastScrape = start
+	e.scrapeSuccess = err == nil
+	if err != nil {
+		a.logger("", "").Warnf("Error collecting metrics: %s", err)
+		e.scrapeErrors++
+		return
+	}
+	a.logger("", "").Debugf("Collected metrics for %d groups (%d copies) in %s", groups, len(copies), e.scrapeDuration)
+	e.copies = copies
+	e.groups = groups
+}
+
+// collectCopyDetails returns the details of the copies of all groups along with the number of groups
+func (a *App) collectCopyDetails() ([]CopyDetail, int, error) {
+	allGroups, err := a.getAllGroups()
+	if err != nil {
+		return nil, 0, err
+	}
+	var copies []CopyDetail
+	for _, g := range allGroups {
+		groupName, err := a.getGroupName(g.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		details, err := a.getGroupCopyDetails(g.ID, groupName)
+		if err != nil {
+			return nil, 0, err
+		}
+		copies = append(copies, details...)
+	}
+	return copies, len(allGroups), nil
+}
+
+// render returns the cached metrics in the prometheus text exposition format
+func (e *exporter) render() []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var b bytes.Buffer
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	copies := append([]CopyDetail(nil), e.copies...)
+	sort.Slice(copies, func(i, j int) bool {
+		if copies[i].GroupName != copies[j].GroupName {
+			return copies[i].GroupName < copies[j].GroupName
+		}
+		return copies[i].Name < copies[j].Name
+	})
+
+	metric("rpda_copy_role", "gauge", "Role of the copy (1 for the current role label).")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_role{%s,role=\"%s\"} 1\n", copyLabels(d), escapeLabel(d.Role))
+	}
+	metric("rpda_copy_image_access_enabled", "gauge", "Whether image access is enabled on the copy.")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_image_access_enabled{%s} %d\n", copyLabels(d), boolValue(d.ImageAccessEnabled))
+	}
+	metric("rpda_copy_direct_access_enabled", "gauge", "Whether direct access is enabled on the copy.")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_direct_access_enabled{%s} %d\n",
+			copyLabels(d), boolValue(d.StorageAccessState == storageDirectAccess))
+	}
+	metric("rpda_copy_lag_seconds", "gauge", "Current lag of the copy behind production.")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_lag_seconds{%s} %g\n", copyLabels(d), d.CurrentRPO.Seconds())
+	}
+	metric("rpda_copy_rpo_seconds", "gauge", "RPO configured on the link of the copy.")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_rpo_seconds{%s} %g\n", copyLabels(d), d.ConfiguredRPO.Seconds())
+	}
+	metric("rpda_copy_journal_usage_ratio", "gauge", "Journal usage of the copy (0-1).")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_journal_usage_ratio{%s} %g\n", copyLabels(d), d.JournalUsage/100)
+	}
+	metric("rpda_copy_journal_lag_bytes", "gauge", "Data in the journal of the copy waiting to be distributed.")
+	for _, d := range copies {
+		fmt.Fprintf(&b, "rpda_copy_journal_lag_bytes{%s} %d\n", copyLabels(d), d.DistributionLag)
+	}
+
+	metric("rpda_groups", "gauge", "Number of consistency groups collected.")
+	fmt.Fprintf(&b, "rpda_groups %d\n", e.groups)
+	metric("rpda_scrape_errors_total", "counter", "Number of failed collections from the RecoverPoint API.")
+	fmt.Fprintf(&b, "rpda_scrape_errors_total %d\n", e.scrapeErrors)
+	metric("rpda_scrape_success", "gauge", "Whether the last collection from the RecoverPoint API succeeded.")
+	fmt.Fprintf(&b, "rpda_scrape_success %d\n", boolValue(e.scrapeSuccess))
+	metric("rpda_scrape_duration_seconds", "gauge", "Duration of the last collection from the RecoverPoint API.")
+	fmt.Fprintf(&b, "rpda_scrape_duration_seconds %g\n", e.scrapeDuration.Seconds())
+	metric("rpda_last_scrape_timestamp_seconds", "gauge", "Unix time of the last collection from the RecoverPoint API.")
+	fmt.Fprintf(&b, "rpda_last_scrape_timestamp_seconds %d\n", e.lastScrape.Unix())
+
+	return b.Bytes()
+}
+
+func copyLabels(d CopyDetail) string {
+	return fmt.Sprintf("group=\"%s\",copy=\"%s\",cluster=\"%d\"", escapeLabel(d.GroupName), escapeLabel(d.Name), d.ClusterUID)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,232 @@
+package rpa
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HistoryFilter selects the audit log records displayed by 'rpda history'
+type HistoryFilter struct {
+	Group  string
+	Since  time.Time
+	User   string
+	Ticket string
+	Action string
+}
+
+// ParseSince parses the start of a history query as a duration before now (ie: 36h or 7d) or a date
+// (ie: 2020-04-20 or 2020-04-20 15:04) in local time
+func ParseSince(s string) (time.Time, error) {
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04:05Z07:00"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (ie: 36h, 7d, 2020-04-20 or 2020-04-20 15:04)", s)
+}
+
+// matches reports whether an audit record is selected by the filter. Run records are only
+// selected when filtering on the 'run' action.
+func (f HistoryFilter) matches(r AuditRecord) bool {
+	if r.Action == "run" && f.Action != "run" {
+		return false
+	}
+	if f.Group != "" && !strings.EqualFold(r.Group, f.Group) {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if f.User != "" && r.OSUser != f.User && r.RPAUser != f.User {
+		return false
+	}
+	if f.Ticket != "" && r.Ticket != f.Ticket {
+		return false
+	}
+	if f.Action != "" && !strings.Contains(r.Action, f.Action) {
+		return false
+	}
+	return true
+}
+
+// loadAudit reads every record of the audit log, returning no records when the log does not exist
+func (a *App) loadAudit() []AuditRecord {
+	f, err := os.Open(a.auditPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // run records include hook output
+	line := 0
+	for scanner.Scan() {
+		line++
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			a.logger("", "").Warnf("Skipping invalid audit log line %d: %s", line, err)
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return records
+}
+
+// printJSON displays a value as indented json
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(data))
+}
+
+// DisplayHistory displays the audit log records selected by a filter as a table or json
+func (a *App) DisplayHistory(f HistoryFilter, asJSON bool) {
+	records := []AuditRecord{}
+	for _, r := range a.loadAudit() {
+		if f.matches(r) {
+			records = append(records, r)
+		}
+	}
+	if asJSON {
+		printJSON(records)
+		return
+	}
+	if len(records) == 0 {
+		fmt.Println("No matching audit records")
+		return
+	}
+	format := "%-19s %-20s %-12s %-12s %-22s %-20s %-20s %-6s %s\n"
+	fmt.Printf(format, "TIME", "RUN", "USER", "TICKET", "ACTION", "GROUP", "COPY", "HTTP", "OUTCOME")
+	for _, r := range records {
+		status := "-"
+		if r.HTTPStatus != 0 {
+			status = strconv.Itoa(r.HTTPStatus)
+		}
+		fmt.Printf(format, r.Time.Local().Format("2006-01-02 15:04:05"), displayState(r.RunID), r.OSUser,
+			displayState(r.Ticket), r.Action, displayState(r.Group), displayState(r.Copy), status, r.Outcome)
+	}
+}
+
+// historyRun is the json representation of 'rpda history show'
+type historyRun struct {
+	Run      *Run          `json:"run,omitempty"`
+	Requests []AuditRecord `json:"requests"`
+}
+
+// DisplayRun displays every step of a run along with its api requests. The run is taken from the audit
+// log, or from the journal when the run did not complete.
+func (a *App) DisplayRun(runID string, asJSON bool) error {
+	h := historyRun{Requests: []AuditRecord{}}
+	var runRecord *AuditRecord
+	for _, r := range a.loadAudit() {
+		if r.RunID != runID {
+			continue
+		}
+		if r.Action == "run" {
+			record := r
+			runRecord = &record
+			h.Run = r.Run
+			continue
+		}
+		h.Requests = append(h.Requests, r)
+	}
+	if h.Run == nil {
+		r, err := a.loadRun(runID)
+		if err != nil {
+			return err
+		}
+		h.Run = r
+	}
+	if h.Run == nil && len(h.Requests) == 0 {
+		return fmt.Errorf("run %s not found", runID)
+	}
+	if asJSON {
+		printJSON(h)
+		return nil
+	}
+
+	layout := "2006-01-02 15:04:05"
+	fmt.Printf("Run:      %s\n", runID)
+	source := runRecord
+	if source == nil && len(h.Requests) > 0 {
+		source = &h.Requests[0]
+	}
+	if source != nil {
+		fmt.Printf("User:     %s (rpa: %s) on %s\n", source.OSUser, source.RPAUser, source.Host)
+		fmt.Printf("Command:  %s\n", source.CommandLine)
+		if source.Ticket != "" {
+			fmt.Printf("Ticket:   %s\n", source.Ticket)
+		}
+		if source.Reason != "" {
+			fmt.Printf("Reason:   %s\n", source.Reason)
+		}
+	}
+	if h.Run != nil {
+		r := h.Run
+		fmt.Printf("Started:  %s\n", r.Started.Local().Format(layout))
+		if r.Completed {
+			fmt.Printf("Finished: %s (took %s)\n", r.Finished.Local().Format(layout), r.Finished.Sub(r.Started).Round(time.Millisecond))
+		} else {
+			fmt.Println("Finished: did not complete (see 'rpda resume')")
+		}
+		for _, g := range r.Groups {
+			copyName := ""
+			if g.Copy != "" {
+				copyName = " " + g.Copy
+			}
+			fmt.Printf("\n%s%s: %s\n", g.Name, copyName, g.Status)
+			if g.WindowOverride {
+				fmt.Println("\tmaintenance window overridden")
+			}
+			for _, s := range g.Steps {
+				result := "ok"
+				if s.Error != "" {
+					result = "failed: " + s.Error
+				} else if !s.Completed {
+					result = "interrupted"
+				}
+				fmt.Printf("\t%s  %-28s %10s  %s\n", s.Started.Local().Format("15:04:05"), s.Name, s.Duration.Round(time.Millisecond), result)
+				for _, line := range strings.Split(strings.TrimRight(s.Output, "\n"), "\n") {
+					if line != "" {
+						fmt.Printf("\t\t| %s\n", line)
+					}
+				}
+			}
+			if g.Error != "" && (len(g.Steps) == 0 || g.Steps[len(g.Steps)-1].Error == "") {
+				fmt.Printf("\terror: %s\n", g.Error)
+			}
+		}
+	}
+	if len(h.Requests) > 0 {
+		fmt.Println("\nAPI requests:")
+		for _, r := range h.Requests {
+			fmt.Printf("\t%s  %-22s %-20s %-20s %d %s (%s)\n", r.Time.Local().Format("15:04:05"), r.Action, r.Group,
+				r.Copy, r.HTTPStatus, r.Outcome, r.Duration.Round(time.Millisecond))
+		}
+	}
+	return nil
+}
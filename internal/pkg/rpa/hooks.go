@@ -0,0 +1,153 @@
+package rpa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultHookTimeout is used when a timeout is not configured for a hook
+const defaultHookTimeout = 5 * time.Minute
+
+// hookCommand is a single hook command resolved for an event & group
+type hookCommand struct {
+	Command string
+	Timeout time.Duration
+}
+
+// command returns the hook command for an event (ie: pre_enable)
+func (h HookCommands) command(event string) string {
+	switch event {
+	case "pre_enable":
+		return h.PreEnable
+	case "post_enable":
+		return h.PostEnable
+	case "pre_finish":
+		return h.PreFinish
+	case "post_finish":
+		return h.PostFinish
+	case "on_failure":
+		return h.OnFailure
+	case "canary":
+		return h.Canary
+	}
+	return ""
+}
+
+// hookCommands returns the global hook command followed by the hook commands of matching groups for an event
+func (a *App) hookCommands(event, groupName string) []hookCommand {
+	var commands []hookCommand
+	timeout := a.Config.Hooks.Timeout
+	if timeout == 0 {
+		timeout = defaultHookTimeout
+	}
+	if c := a.Config.Hooks.command(event); c != "" {
+		commands = append(commands, hookCommand{c, timeout})
+	}
+	for _, g := range a.Config.Hooks.Groups {
+		if g.Pattern == nil || !g.Pattern.MatchString(groupName) {
+			continue
+		}
+		if c := g.command(event); c != "" {
+			t := g.Timeout
+			if t == 0 {
+				t = timeout
+			}
+			commands = append(commands, hookCommand{c, t})
+		}
+	}
+	return commands
+}
+
+// runHooks runs the hook commands of an event for a task. The task is provided to each command as a
+// json payload on stdin. An error is returned when a command fails or exceeds its timeout.
+func (a *App) runHooks(event string, t Task, failure error) error {
+	payload := HookPayload{Event: event, Task: t, CheckMode: a.Config.CheckMode}
+	if failure != nil {
+		payload.Error = failure.Error()
+	}
+	data, err := json.Marshal(&payload)
+	if err != nil {
+		return err
+	}
+
+	commands := a.hookCommands(event, t.GroupName)
+	if len(commands) == 0 {
+		return nil
+	}
+	return a.stepWithOutput(t.GroupName, "hook_"+event, func() (string, error) {
+		var outputs []string
+		for _, h := range commands {
+			fmt.Printf("%s - Running %s hook: %s\n", t.GroupName, event, h.Command)
+			output, err := runHookCommand(h, data)
+			outputs = append(outputs, output)
+			for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+				if line != "" {
+					fmt.Printf("%s - %s: %s\n", t.GroupName, event, line)
+				}
+			}
+			if err != nil {
+				return strings.Join(outputs, ""), fmt.Errorf("%s hook failed: %s", event, err)
+			}
+		}
+		return strings.Join(outputs, ""), nil
+	})
+}
+
+// runHookCommand runs a hook command using the system shell and returns its combined output
+func runHookCommand(h hookCommand, payload []byte) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	c := exec.CommandContext(ctx, shell, flag, h.Command)
+	c.Stdin = bytes.NewReader(payload)
+
+	// output is written to a file rather than a pipe so that a timed out command does not wait
+	// on child processes of the shell which still hold the output open
+	output, err := ioutil.TempFile("", "rpda-hook")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+	c.Stdout = output
+	c.Stderr = output
+
+	err = c.Run()
+	out, _ := ioutil.ReadFile(output.Name())
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(out), fmt.Errorf("timeout of %s exceeded", h.Timeout)
+	}
+	return string(out), err
+}
+
+// runOperation runs the pre hooks, the operation & the post hooks for a task. A failing pre hook vetoes the
+// operation. The on_failure hooks are run when any of the hooks or the operation fail.
+func (a *App) runOperation(operation string, t Task, fn func() error) error {
+	err := a.runHooks("pre_"+operation, t, nil)
+	if err != nil {
+		err = fmt.Errorf("%s vetoed: %s", operation, err)
+	} else {
+		err = fn()
+		if err == nil {
+			err = a.runHooks("post_"+operation, t, nil)
+		}
+	}
+	if err != nil {
+		if hookErr := a.runHooks("on_failure", t, err); hookErr != nil {
+			a.logger(t.GroupName, t.CopyName).Warnf("%s - %s", t.GroupName, hookErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,505 @@
+package rpa
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+)
+
+// group run statuses
+const (
+	groupPending = "pending"
+	groupRunning = "running"
+	groupDone    = "done"
+	groupFailed  = "failed"
+)
+
+// newRunID returns a sortable unique identifier for a run (ie: 20200420-101500-3f9a)
+func newRunID() string {
+	b := make([]byte, 2)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// journalDir returns the directory of the journal, which holds a file per incomplete run
+// (default: $HOME/.rpda-journal)
+func (a *App) journalDir() string {
+	if a.Config.JournalPath != "" {
+		return a.Config.JournalPath
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filepath.Join(home, ".rpda-journal")
+}
+
+// runPath returns the path of the journal file of a run
+func (a *App) runPath(runID string) string {
+	return filepath.Join(a.journalDir(), runID+".json")
+}
+
+// loadRun reads a run from the journal, returning nil when the run is not in the journal
+func (a *App) loadRun(runID string) (*Run, error) {
+	data, err := ioutil.ReadFile(a.runPath(runID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Run{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("unable to read journal %s: %s", a.runPath(runID), err)
+	}
+	return r, nil
+}
+
+// incompleteRuns returns the runs in the journal which did not complete, oldest first
+func (a *App) incompleteRuns() ([]*Run, error) {
+	files, err := ioutil.ReadDir(a.journalDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Run
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		r, err := a.loadRun(strings.TrimSuffix(f.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if r != nil && !r.Completed {
+			runs = append(runs, r)
+		}
+	}
+	// run ids are sortable by the time the run started
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID < runs[j].ID
+	})
+	return runs, nil
+}
+
+// saveRun writes the current run to its journal file, removing the file once the run completed (completed
+// runs are kept in the audit log). The caller must hold runMu.
+func (a *App) saveRun() {
+	if a.Config.CheckMode || a.run == nil {
+		return
+	}
+	path := a.runPath(a.run.ID)
+	if a.run.Completed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logEntry(a.run.ID, "", "").Warnf("Unable to remove journal: %s", err)
+		}
+		return
+	}
+	data, err := json.MarshalIndent(a.run, "", "  ")
+	if err != nil {
+		logEntry(a.run.ID, "", "").Fatal(err)
+	}
+	if err := os.MkdirAll(a.journalDir(), 0700); err != nil {
+		logEntry(a.run.ID, "", "").Warnf("Unable to write journal: %s", err)
+		return
+	}
+	// write to a temporary file & rename so an interruption cannot leave a partial journal
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		logEntry(a.run.ID, "", "").Warnf("Unable to write journal: %s", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		logEntry(a.run.ID, "", "").Warnf("Unable to write journal: %s", err)
+	}
+}
+
+// beginRun starts recording a new run for a command & its groups (when known in advance).
+// When a run is already in progress (ie: exec enables then finishes), the existing run is used and
+// false is returned so that only the owner of the run ends it.
+func (a *App) beginRun(command string, groups []string) bool {
+	a.requireTicket()
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.run != nil && !a.run.Completed {
+		for _, name := range groups {
+			a.groupRun(name)
+		}
+		return false
+	}
+
+	a.run = &Run{
+		ID:      newRunID(),
+		Command: command,
+		Started: time.Now(),
+	}
+	a.run.CopyName = a.CopyName
+	a.run.Reason = a.Config.Reason
+	a.run.Ticket = a.Config.Ticket
+	if a.CopyRegexp != nil {
+		a.run.CopyRegexp = a.CopyRegexp.String()
+	}
+	for _, name := range groups {
+		a.groupRun(name)
+	}
+	// write the report of the run when a fatal error exits before the run ended
+	a.abortOnce.Do(func() { log.RegisterExitHandler(a.abortRun) })
+	logEntry(a.run.ID, "", "").Debugf("Started run %s (%s)", a.run.ID, command)
+	if runs, err := a.incompleteRuns(); err == nil && len(runs) > 0 && !a.Config.CheckMode {
+		logEntry(a.run.ID, "", "").Warnf("%d previous runs did not complete, see 'rpda resume'", len(runs))
+	}
+	a.saveRun()
+	a.notifyRunStarted()
+	return true
+}
+
+// endRun marks the current run as completed when called by the owner of the run. When deferred, a
+// panic is re-raised once the run was recorded as aborted.
+func (a *App) endRun(owner bool) {
+	if !owner {
+		return
+	}
+	if r := recover(); r != nil {
+		a.abortRun()
+		panic(r)
+	}
+	a.runMu.Lock()
+	if a.run == nil {
+		a.runMu.Unlock()
+		return
+	}
+	a.run.Finished = time.Now()
+	a.run.Completed = true
+	a.saveRun()
+	a.auditRun()
+	a.notifyRunFinished()
+	run := a.run
+	a.runMu.Unlock()
+	// wait for the notifications of the run to be delivered before the command exits
+	a.flushNotifications()
+	a.writeReport(run)
+	a.writeJUnit(run)
+}
+
+// abortRun records the current run as finished (but not completed, so it can be resumed), notifies that
+// the run finished & writes its report & junit results when the command exits with a fatal error (or
+// panics) before the run ended. It is registered as a logrus exit handler by beginRun.
+func (a *App) abortRun() {
+	// the fatal error may have been logged while holding runMu, which would never be released
+	if !lockWithin(&a.runMu, time.Second) {
+		return
+	}
+	run := a.run
+	if run == nil || run.Completed {
+		a.runMu.Unlock()
+		return
+	}
+	run.Finished = time.Now()
+	a.saveRun()
+	a.notifyRunFinished()
+	a.runMu.Unlock()
+	a.flushNotifications()
+	a.writeReport(run)
+	a.writeJUnit(run)
+}
+
+// lockWithin locks a mutex, giving up after a timeout
+func lockWithin(mu *sync.Mutex, timeout time.Duration) bool {
+	locked := make(chan struct{})
+	go func() {
+		mu.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// groupRun returns the record of a group within the current run, adding it when missing.
+// The caller must hold runMu.
+func (a *App) groupRun(groupName string) *GroupRun {
+	for _, g := range a.run.Groups {
+		if g.Name == groupName {
+			return g
+		}
+	}
+	g := &GroupRun{Name: groupName, Status: groupPending}
+	a.run.Groups = append(a.run.Groups, g)
+	return g
+}
+
+// step runs fn as a named step of a group, recording its timing & result in the journal.
+// When resuming a run, steps which already completed are skipped.
+func (a *App) step(groupName, name string, fn func() error) error {
+	return a.stepWithOutput(groupName, name, func() (string, error) {
+		return "", fn()
+	})
+}
+
+// stepWithOutput is step for functions which produce output to be recorded (ie: hooks)
+func (a *App) stepWithOutput(groupName, name string, fn func() (string, error)) error {
+	a.runMu.Lock()
+	if a.run == nil {
+		a.runMu.Unlock()
+		_, err := fn()
+		return err
+	}
+	g := a.groupRun(groupName)
+	for _, s := range g.Steps[g.OperationStep:] {
+		if a.resuming && s.Name == name && s.succeeded() {
+			a.runMu.Unlock()
+			a.logger(groupName, "").Debugf("%s - Skipping completed step %s", groupName, name)
+			return nil
+		}
+	}
+	g.Status = groupRunning
+	s := &Step{Name: name, Started: time.Now()}
+	g.Steps = append(g.Steps, s)
+	a.saveRun()
+	a.runMu.Unlock()
+
+	output, err := fn()
+
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	s.Duration = time.Since(s.Started)
+	s.Output = output
+	s.Completed = true
+	if err != nil {
+		s.Error = err.Error()
+	}
+	a.saveRun()
+	return err
+}
+
+// succeeded reports whether a step completed without error. Steps which were interrupted (ie: the
+// process was killed) are not completed and are performed again when resuming.
+func (s *Step) succeeded() bool {
+	return s.Completed && s.Error == ""
+}
+
+// completeGroup records the outcome of an operation on a group within the current run
+func (a *App) completeGroup(groupName, copyName string, err error) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.run == nil {
+		return
+	}
+	g := a.groupRun(groupName)
+	if copyName != "" {
+		g.Copy = copyName
+	}
+	g.Status = groupDone
+	g.Error = ""
+	if err != nil {
+		g.Status = groupFailed
+		g.Error = err.Error()
+	}
+	a.saveRun()
+}
+
+// startOperation records the operation (enable or finish) & copy of a group within the current run so
+// that an interrupted run can be resumed. Steps recorded before the operation are not skipped when resuming.
+func (a *App) startOperation(groupName, operation, copyName string) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.run == nil {
+		return
+	}
+	g := a.groupRun(groupName)
+	if !a.resuming || g.Operation != operation {
+		g.OperationStep = len(g.Steps)
+	}
+	g.Operation = operation
+	g.Copy = copyName
+	a.saveRun()
+}
+
+// changedByRun reports whether image access was enabled on a group by the current run
+func (a *App) changedByRun(groupName string) bool {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.run == nil {
+		return false
+	}
+	for _, s := range a.groupRun(groupName).Steps {
+		if s.Name == "image_access" && s.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeTarget is a group of an interrupted run along with the operation which will be resumed
+type resumeTarget struct {
+	Group     *GroupRun
+	Operation string
+}
+
+// resumeTargets returns the groups of a run which will be resumed. The remaining groups of enable, finish &
+// restore runs continue their operation. Commands & runbook stages of exec & run are not repeated, instead the
+// copies left in direct access by the run are returned to replication.
+func resumeTargets(r *Run) []resumeTarget {
+	var targets []resumeTarget
+	for _, g := range r.Groups {
+		operation := g.Operation
+		if operation == "" && (r.Command == "enable" || r.Command == "finish") {
+			operation = r.Command
+		}
+		switch r.Command {
+		case "exec", "run":
+			switch {
+			case operation == "enable" && enabledByOperation(g):
+				fmt.Printf("%s - Left in direct access by the run, finishing\n", g.Name)
+				targets = append(targets, resumeTarget{g, "finish"})
+			case operation == "finish" && g.Status != groupDone:
+				fmt.Printf("%s - %s\n", g.Name, resumePoint(g))
+				targets = append(targets, resumeTarget{g, "finish"})
+			default:
+				fmt.Printf("%s - Not left in direct access by the run, skipping\n", g.Name)
+			}
+		default:
+			switch {
+			case g.Status == groupDone:
+				fmt.Printf("%s - Already completed, skipping\n", g.Name)
+			case operation == "":
+				fmt.Printf("%s - Operation unknown, skipping\n", g.Name)
+			default:
+				fmt.Printf("%s - %s\n", g.Name, resumePoint(g))
+				targets = append(targets, resumeTarget{g, operation})
+			}
+		}
+	}
+	return targets
+}
+
+// enabledByOperation reports whether image access was enabled by the last operation of a group
+func enabledByOperation(g *GroupRun) bool {
+	for _, s := range g.Steps[g.OperationStep:] {
+		if s.Name == "image_access" && s.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayIncompleteRuns lists the runs in the journal which did not complete & can be resumed
+func (a *App) DisplayIncompleteRuns() error {
+	runs, err := a.incompleteRuns()
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Println("Nothing to resume, all runs completed")
+		return nil
+	}
+	fmt.Printf("%-22s %-8s %-20s %s\n", "RUN", "COMMAND", "STARTED", "GROUPS")
+	for _, r := range runs {
+		var names []string
+		for _, g := range r.Groups {
+			names = append(names, g.Name)
+		}
+		fmt.Printf("%-22s %-8s %-20s %s\n", r.ID, r.Command, r.Started.Format("2006-01-02 15:04:05"), strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// Resume continues an interrupted run recorded in the journal. Groups which completed are skipped and the
+// remaining groups continue from the last completed step of their operation.
+func (a *App) Resume(runID string) error {
+	r, err := a.loadRun(runID)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		return fmt.Errorf("run %s not found in the journal %s", runID, a.journalDir())
+	}
+	if r.Completed {
+		fmt.Printf("Nothing to resume, run %s completed\n", r.ID)
+		return nil
+	}
+
+	fmt.Printf("Resuming run %s (%s started %s)\n", r.ID, r.Command, r.Started.Format("2006-01-02 15:04:05"))
+	targets := resumeTargets(r)
+	if a.Config.CheckMode {
+		return nil
+	}
+	a.requireTicket()
+	ids, err := a.getGroupIDsByName()
+	if err != nil {
+		return err
+	}
+
+	a.runMu.Lock()
+	a.run = r
+	a.resuming = true
+	a.runMu.Unlock()
+
+	start := time.Now()
+	failed := false
+	for _, target := range targets {
+		name := target.Group.Name
+		groupID, ok := ids[name]
+		if !ok {
+			a.logger(name, "").Warnf("%s - Consistency group not found", name)
+			failed = true
+			continue
+		}
+		// use the copy recorded for the group, falling back to the copy selection of the run
+		a.CopyName = target.Group.Copy
+		a.CopyRegexp = nil
+		if a.CopyName == "" {
+			a.CopyName = r.CopyName
+			if r.CopyRegexp != "" {
+				a.CopyRegexp = regexp.MustCompile(r.CopyRegexp)
+			}
+		}
+		operation := a.enableGroup
+		if target.Operation == "finish" {
+			operation = a.finishGroup
+		}
+		if err := operation(groupID, name); err != nil {
+			a.logger(name, "").Warnf("%s - %s\n", name, err)
+			failed = true
+		}
+	}
+	a.endRun(true)
+	elapsed := time.Since(start)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	if failed {
+		return errors.New("one or more groups failed")
+	}
+	return nil
+}
+
+// resumePoint describes where a group will be resumed from
+func resumePoint(g *GroupRun) string {
+	var last string
+	for _, s := range g.Steps {
+		if s.succeeded() {
+			last = s.Name
+		}
+	}
+	if last == "" {
+		return "Not started, starting from the beginning"
+	}
+	return fmt.Sprintf("Resuming after step %s", last)
+}
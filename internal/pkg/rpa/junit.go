@@ -0,0 +1,141 @@
+package rpa
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// junitSuites is the root element of a junit xml file
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite is the test suite of a run
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+// junitProperty is a property of a test suite (ie: the ticket of the run)
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitCase is a single phase of the operation on a consistency group
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitMessage is the failure or skipped element of a test case
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSeconds formats a duration as junit seconds
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// junitGroupCases returns a test case for each step (image access, poll, direct access, hooks, finish..)
+// performed on a group. Groups which failed before a step was performed (ie: outside of a maintenance
+// window) or which were not reached are reported as a single test case named after the command.
+func junitGroupCases(command string, g *GroupRun) []junitCase {
+	var cases []junitCase
+	stepFailed := false
+	for _, s := range g.Steps {
+		c := junitCase{ClassName: g.Name, Name: s.Name, Time: junitSeconds(s.Duration), SystemOut: s.Output}
+		if s.Error != "" {
+			c.Failure = &junitMessage{Message: s.Error, Text: s.Error}
+			stepFailed = true
+		} else if !s.Completed {
+			c.Failure = &junitMessage{Message: "interrupted", Text: "interrupted"}
+			stepFailed = true
+		}
+		cases = append(cases, c)
+	}
+	switch {
+	case g.Status == groupFailed && !stepFailed:
+		cases = append(cases, junitCase{ClassName: g.Name, Name: command, Time: junitSeconds(0),
+			Failure: &junitMessage{Message: g.Error, Text: g.Error}})
+	case g.Status != groupDone && g.Status != groupFailed:
+		cases = append(cases, junitCase{ClassName: g.Name, Name: command, Time: junitSeconds(0),
+			Skipped: &junitMessage{Message: "not reached"}})
+	case len(cases) == 0:
+		// no changes were required (ie: already enabled) or check mode
+		cases = append(cases, junitCase{ClassName: g.Name, Name: command, Time: junitSeconds(0)})
+	}
+	return cases
+}
+
+// writeJUnit writes the result of a run as a junit xml test suite with a test case for each phase of the
+// operation on each group
+func (a *App) writeJUnit(run *Run) {
+	if a.JUnit == "" || run == nil {
+		return
+	}
+	host, _ := os.Hostname()
+	s := junitSuite{
+		Name:      fmt.Sprintf("rpda %s %s", run.Command, run.ID),
+		Time:      junitSeconds(run.Finished.Sub(run.Started)),
+		Timestamp: run.Started.UTC().Format("2006-01-02T15:04:05"),
+		Hostname:  host,
+	}
+	s.Properties = append(s.Properties, junitProperty{"run_id", run.ID}, junitProperty{"operator", currentUser()})
+	if run.Ticket != "" {
+		s.Properties = append(s.Properties, junitProperty{"ticket", run.Ticket})
+	}
+	if run.Reason != "" {
+		s.Properties = append(s.Properties, junitProperty{"reason", run.Reason})
+	}
+	if a.Config.CheckMode {
+		s.Properties = append(s.Properties, junitProperty{"check_mode", "true"})
+	}
+	var cases []junitCase
+	for _, g := range run.Groups {
+		cases = append(cases, junitGroupCases(run.Command, g)...)
+	}
+	if !run.Completed {
+		// the run was aborted by a fatal error
+		msg := "the run was aborted before it completed, see 'rpda resume'"
+		cases = append(cases, junitCase{ClassName: "rpda", Name: run.Command, Time: s.Time,
+			Failure: &junitMessage{Message: msg, Text: msg}})
+	}
+	for _, c := range cases {
+		s.Tests++
+		if c.Failure != nil {
+			s.Failures++
+		}
+		if c.Skipped != nil {
+			s.Skipped++
+		}
+		s.Cases = append(s.Cases, c)
+	}
+
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{s}}, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(a.JUnit, append([]byte(xml.Header), append(data, '\n')...), 0644)
+	}
+	if err != nil {
+		logEntry(run.ID, "", "").Errorf("Unable to write junit results %s: %s", a.JUnit, err)
+		return
+	}
+	fmt.Printf("JUnit results written to %s\n", a.JUnit)
+}
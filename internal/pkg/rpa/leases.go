@@ -0,0 +1,233 @@
+package rpa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultLeaseWarning is used when 'leases.warning' is not configured
+const defaultLeaseWarning = time.Hour
+
+// leasesPath returns the path of the leases file (default: $HOME/.rpda-leases.json)
+func (a *App) leasesPath() string {
+	if a.Config.LeasesPath != "" {
+		return a.Config.LeasesPath
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filepath.Join(home, ".rpda-leases.json")
+}
+
+// loadLeases reads the leases file, returning no leases when the file does not exist
+func (a *App) loadLeases() ([]Lease, error) {
+	data, err := ioutil.ReadFile(a.leasesPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var leases []Lease
+	if err := json.Unmarshal(data, &leases); err != nil {
+		return nil, fmt.Errorf("unable to read leases %s: %s", a.leasesPath(), err)
+	}
+	return leases, nil
+}
+
+// saveLeases writes the leases file
+func (a *App) saveLeases(leases []Lease) {
+	data, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := a.leasesPath()
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		a.logger("", "").Warnf("Unable to write leases: %s", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		a.logger("", "").Warnf("Unable to write leases: %s", err)
+	}
+}
+
+// addLease records (or renews) a lease on a copy enabled by this run. Leases held by another owner are kept.
+func (a *App) addLease(groupName, copyName string) {
+	if a.Lease == 0 || a.Config.CheckMode {
+		return
+	}
+	a.leaseMu.Lock()
+	defer a.leaseMu.Unlock()
+	host, _ := os.Hostname()
+	now := time.Now()
+	l := Lease{
+		Group:   groupName,
+		Copy:    copyName,
+		Owner:   currentUser(),
+		Host:    host,
+		Created: now,
+		Expires: now.Add(a.Lease),
+	}
+	existingLeases, err := a.loadLeases()
+	if err != nil {
+		a.logger(groupName, copyName).Warnf("%s - Unable to lease copy %s: %s", groupName, copyName, err)
+		return
+	}
+	leases := []Lease{l}
+	for _, existing := range existingLeases {
+		if existing.Group != groupName || existing.Copy != copyName {
+			leases = append(leases, existing)
+			continue
+		}
+		// a lease is only renewed by its owner
+		if existing.Owner != l.Owner || existing.Host != l.Host {
+			a.logger(groupName, copyName).Warnf("%s - Copy %s is leased by %s@%s until %s, lease not changed",
+				groupName, copyName, existing.Owner, existing.Host, existing.Expires.Format("2006-01-02 15:04:05"))
+			return
+		}
+	}
+	a.saveLeases(leases)
+	fmt.Printf("%s - Leased copy %s until %s\n", groupName, copyName, l.Expires.Format("2006-01-02 15:04:05"))
+}
+
+// removeLease removes the lease on a copy which has been finished
+func (a *App) removeLease(groupName, copyName string) {
+	if a.Config.CheckMode {
+		return
+	}
+	a.leaseMu.Lock()
+	defer a.leaseMu.Unlock()
+	existingLeases, err := a.loadLeases()
+	if err != nil {
+		a.logger(groupName, copyName).Warnf("%s - Unable to remove the lease of copy %s: %s", groupName, copyName, err)
+		return
+	}
+	var leases []Lease
+	removed := false
+	for _, l := range existingLeases {
+		if l.Group == groupName && l.Copy == copyName {
+			removed = true
+			continue
+		}
+		leases = append(leases, l)
+	}
+	if removed {
+		a.saveLeases(leases)
+		a.logger(groupName, copyName).Debugf("%s - Removed lease for copy %s", groupName, copyName)
+	}
+}
+
+// sortedLeases returns the leases ordered by expiry
+func (a *App) sortedLeases() []Lease {
+	leases, err := a.loadLeases()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Expires.Before(leases[j].Expires)
+	})
+	return leases
+}
+
+// DisplayLeases lists the active leases along with their age & time until expiry
+func (a *App) DisplayLeases() {
+	leases := a.sortedLeases()
+	if len(leases) == 0 {
+		fmt.Println("No active leases")
+		return
+	}
+	now := time.Now()
+	fmt.Printf("%-24s %-24s %-16s %-10s %s\n", "GROUP", "COPY", "OWNER", "AGE", "EXPIRES")
+	for _, l := range leases {
+		expires := "in " + l.Expires.Sub(now).Round(time.Minute).String()
+		if now.After(l.Expires) {
+			expires = "EXPIRED " + now.Sub(l.Expires).Round(time.Minute).String() + " ago"
+		}
+		owner := l.Owner
+		if l.Host != "" {
+			owner += "@" + l.Host
+		}
+		fmt.Printf("%-24s %-24s %-16s %-10s %s\n", l.Group, l.Copy, owner, now.Sub(l.Created).Round(time.Minute), expires)
+	}
+}
+
+// ReapLeases finishes the copies of expired leases & warns about leases which expire soon
+func (a *App) ReapLeases() error {
+	start := time.Now()
+	warning := a.Config.LeaseWarning
+	if warning == 0 {
+		warning = defaultLeaseWarning
+	}
+	var expired []Lease
+	for _, l := range a.sortedLeases() {
+		remaining := time.Until(l.Expires)
+		switch {
+		case remaining <= 0:
+			expired = append(expired, l)
+		case remaining <= warning:
+			a.logger(l.Group, l.Copy).Warnf("%s - Lease on copy %s (%s) expires in %s", l.Group, l.Copy, l.Owner, remaining.Round(time.Minute))
+		}
+	}
+	if len(expired) == 0 {
+		fmt.Println("No expired leases")
+		return nil
+	}
+
+	ids, err := a.getGroupIDsByName()
+	if err != nil {
+		return err
+	}
+	var groupNames []string
+	for _, l := range expired {
+		groupNames = append(groupNames, l.Group)
+	}
+	owner := a.beginRun("finish", groupNames)
+	defer a.endRun(owner)
+
+	failed := 0
+	for _, l := range expired {
+		fmt.Printf("%s - Lease on copy %s (%s) expired %s ago, finishing\n", l.Group, l.Copy, l.Owner, time.Since(l.Expires).Round(time.Minute))
+		groupID, ok := ids[l.Group]
+		if !ok {
+			a.logger(l.Group, l.Copy).Warnf("%s - Consistency group not found, removing lease", l.Group)
+			a.removeLease(l.Group, l.Copy)
+			continue
+		}
+		a.CopyName = l.Copy
+		a.CopyRegexp = nil
+		// the copy may have been finished since (ie: from the GUI), only the lease remains
+		copySettings, err := a.getGroupRequestedCopy(groupID)
+		if err != nil {
+			a.logger(l.Group, l.Copy).Warnf("%s - %s\n", l.Group, err)
+			failed++
+			continue
+		}
+		if !copySettings.ImageAccessInformation.ImageAccessEnabled {
+			fmt.Printf("%s - Copy %s is no longer in image access, removing lease\n", l.Group, l.Copy)
+			a.removeLease(l.Group, l.Copy)
+			a.completeGroup(l.Group, l.Copy, nil)
+			continue
+		}
+		if err := a.finishGroup(groupID, l.Group); err != nil {
+			a.logger(l.Group, l.Copy).Warnf("%s - %s\n", l.Group, err)
+			failed++
+		}
+	}
+	elapsed := time.Since(start)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	if failed > 0 {
+		return errors.New("one or more expired leases could not be finished")
+	}
+	return nil
+}
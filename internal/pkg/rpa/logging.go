@@ -0,0 +1,366 @@
+package rpa
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// defaults used when the 'logging' section is not fully configured
+const (
+	defaultSyslogTag     = "rpda"
+	defaultLogMaxSize    = 10 // megabytes
+	defaultLogMaxBackups = 5
+)
+
+// syslog reconnection timings, entries are dropped while waiting to reconnect
+const (
+	syslogDialTimeout = 1 * time.Second
+	syslogMinBackoff  = 1 * time.Second
+	syslogMaxBackoff  = 1 * time.Minute
+)
+
+// contextFields are the fields attached to entries by App.logger. They are part of the message already
+// (ie: "ERP_DB_CG - ...") so they are omitted from the console in text format.
+var contextFields = []string{"run_id", "group", "copy"}
+
+// syslogFacilities maps facility names to RFC 5424 facility codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities maps log levels to RFC 5424 severities
+var syslogSeverities = map[log.Level]int{
+	log.PanicLevel: 2, // critical
+	log.FatalLevel: 2, // critical
+	log.ErrorLevel: 3,
+	log.WarnLevel:  4,
+	log.InfoLevel:  6,
+	log.DebugLevel: 7,
+	log.TraceLevel: 7,
+}
+
+// ConfigureLogging sets the console format & adds the syslog & log file backends from the 'logging'
+// section of the config
+func ConfigureLogging() {
+	var l Logging
+	if err := viper.UnmarshalKey("logging", &l); err != nil {
+		log.Fatalf("Invalid logging configuration: %s", err)
+	}
+	switch strings.ToLower(l.Format) {
+	case "", "text":
+		log.SetFormatter(&consoleFormatter{&log.TextFormatter{}})
+	case "json":
+		log.SetFormatter(&jsonFormatter{&log.JSONFormatter{}})
+	default:
+		log.Fatalf("Invalid logging format '%s' (expected text or json)", l.Format)
+	}
+	if l.Syslog.Network != "" || l.Syslog.Address != "" {
+		h, err := newSyslogHook(l.Syslog)
+		if err != nil {
+			log.Fatalf("Invalid syslog configuration: %s", err)
+		}
+		// an unreachable syslog server must not prevent changes, the hook reconnects when logging
+		if err := h.connect(); err != nil {
+			log.Warnf("Unable to connect to syslog %s, will retry: %s", h.address, err)
+		}
+		log.AddHook(h)
+	}
+	if l.File.Path != "" {
+		h, err := newFileHook(l.File)
+		if err != nil {
+			log.Fatalf("Invalid log file configuration: %s", err)
+		}
+		log.AddHook(h)
+	}
+}
+
+// logger returns a log entry with the id of the current run along with the group & copy (when provided)
+// attached as fields. Callers holding runMu must use logEntry.
+func (a *App) logger(groupName, copyName string) *log.Entry {
+	runID := ""
+	a.runMu.Lock()
+	if a.run != nil {
+		runID = a.run.ID
+	}
+	a.runMu.Unlock()
+	return logEntry(runID, groupName, copyName)
+}
+
+// logEntry returns a log entry with the non empty run id, group & copy attached as fields
+func logEntry(runID, groupName, copyName string) *log.Entry {
+	fields := log.Fields{}
+	for i, v := range []string{runID, groupName, copyName} {
+		if v != "" {
+			fields[contextFields[i]] = v
+		}
+	}
+	return log.WithFields(fields)
+}
+
+// consoleFormatter keeps the human readable console output by omitting the context fields
+type consoleFormatter struct {
+	*log.TextFormatter
+}
+
+// Format formats an entry without its context fields
+func (f *consoleFormatter) Format(entry *log.Entry) ([]byte, error) {
+	data := make(log.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	for _, k := range contextFields {
+		delete(data, k)
+	}
+	e := *entry
+	e.Data = data
+	return f.TextFormatter.Format(&e)
+}
+
+// jsonFormatter formats entries as json without the trailing newlines of their messages
+type jsonFormatter struct {
+	*log.JSONFormatter
+}
+
+// Format formats an entry as json
+func (f *jsonFormatter) Format(entry *log.Entry) ([]byte, error) {
+	e := *entry
+	e.Message = strings.TrimRight(entry.Message, "\n")
+	return f.JSONFormatter.Format(&e)
+}
+
+// syslogHook sends log entries to a syslog server as RFC 5424 messages. Hooks are fired with the
+// logger locked so writes are not concurrent. When the server is unreachable, entries are dropped and the
+// connection is retried with an increasing backoff so that logging is never held up for long.
+type syslogHook struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string
+	conn     net.Conn
+	retryAt  time.Time     // time of the next connection attempt after a failure
+	backoff  time.Duration // time between connection attempts
+}
+
+// newSyslogHook validates the syslog configuration
+func newSyslogHook(c LogSyslog) (*syslogHook, error) {
+	network := strings.ToLower(c.Network)
+	if network != "udp" && network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("invalid network '%s' (expected udp, tcp or unix)", c.Network)
+	}
+	if c.Address == "" {
+		return nil, fmt.Errorf("an address is required")
+	}
+	facility := "user"
+	if c.Facility != "" {
+		facility = strings.ToLower(c.Facility)
+	}
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("invalid facility '%s'", c.Facility)
+	}
+	h := &syslogHook{network: network, address: c.Address, facility: code, tag: c.Tag}
+	if h.tag == "" {
+		h.tag = defaultSyslogTag
+	}
+	if h.hostname, _ = os.Hostname(); h.hostname == "" {
+		h.hostname = "-"
+	}
+	return h, nil
+}
+
+// connect dials the syslog server, backing off after a failure. Unix sockets are tried as datagram
+// sockets (ie: /dev/log) first.
+func (h *syslogHook) connect() error {
+	var err error
+	if h.network == "unix" {
+		if h.conn, err = net.Dial("unixgram", h.address); err == nil {
+			h.backoff = 0
+			return nil
+		}
+	}
+	h.conn, err = net.DialTimeout(h.network, h.address, syslogDialTimeout)
+	if err != nil {
+		h.conn = nil
+		h.backoff *= 2
+		if h.backoff < syslogMinBackoff {
+			h.backoff = syslogMinBackoff
+		}
+		if h.backoff > syslogMaxBackoff {
+			h.backoff = syslogMaxBackoff
+		}
+		h.retryAt = time.Now().Add(h.backoff)
+		return err
+	}
+	h.backoff = 0
+	return nil
+}
+
+// Levels returns every level, the level of the logger applies
+func (h *syslogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire sends an entry, reconnecting when the connection was lost. Entries are dropped while waiting
+// to reconnect.
+func (h *syslogHook) Fire(entry *log.Entry) error {
+	msg := h.format(entry)
+	if h.network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg) // octet counting framing (RFC 6587)
+	}
+	if h.conn != nil {
+		if _, err := h.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		h.conn.Close()
+		h.conn = nil
+	}
+	if time.Now().Before(h.retryAt) {
+		return nil
+	}
+	if err := h.connect(); err != nil {
+		return err
+	}
+	_, err := h.conn.Write([]byte(msg))
+	return err
+}
+
+// format formats an entry as an RFC 5424 message with its fields as structured data
+// (ie: <12>1 2020-04-20T22:00:14.021Z ops01 rpda 4242 - [fields@32473 group="ERP_DB_CG"] message)
+func (h *syslogHook) format(entry *log.Entry) string {
+	pri := h.facility*8 + syslogSeverities[entry.Level]
+	sd := "-"
+	if len(entry.Data) > 0 {
+		keys := make([]string, 0, len(entry.Data))
+		for k := range entry.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		b.WriteString("[fields@32473")
+		for _, k := range keys {
+			fmt.Fprintf(&b, " %s=\"%s\"", sdName(k), sdEscape(fmt.Sprint(entry.Data[k])))
+		}
+		b.WriteString("]")
+		sd = b.String()
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s\n", pri, entry.Time.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		h.hostname, h.tag, os.Getpid(), sd, strings.TrimRight(entry.Message, "\n"))
+}
+
+// sdName removes the characters not allowed in structured data parameter names
+func sdName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// sdEscape escapes a structured data parameter value
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// fileHook writes log entries as json lines to a file, rotating it when it reaches the maximum size.
+// Hooks are fired with the logger locked so writes are not concurrent.
+type fileHook struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	formatter  log.Formatter
+	file       *os.File
+	size       int64
+}
+
+// newFileHook opens (or creates) the log file
+func newFileHook(c LogFile) (*fileHook, error) {
+	h := &fileHook{
+		path:       c.Path,
+		maxSize:    int64(c.MaxSize) * 1024 * 1024,
+		maxBackups: c.MaxBackups,
+		formatter:  &jsonFormatter{&log.JSONFormatter{}},
+	}
+	if h.maxSize <= 0 {
+		h.maxSize = defaultLogMaxSize * 1024 * 1024
+	}
+	if h.maxBackups <= 0 {
+		h.maxBackups = defaultLogMaxBackups
+	}
+	if err := h.open(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// open opens the log file for appending
+func (h *fileHook) open() error {
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	h.file = f
+	h.size = info.Size()
+	return nil
+}
+
+// rotate renames the log file to <path>.1, shifting older files up to <path>.<maxBackups>, and opens a
+// new log file
+func (h *fileHook) rotate() error {
+	h.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", h.path, h.maxBackups))
+	for i := h.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", h.path, i), fmt.Sprintf("%s.%d", h.path, i+1))
+	}
+	if err := os.Rename(h.path, h.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return h.open()
+}
+
+// Levels returns every level, the level of the logger applies
+func (h *fileHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire writes an entry, rotating the log file first when the entry would exceed the maximum size
+func (h *fileHook) Fire(entry *log.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	if h.file == nil {
+		if err := h.open(); err != nil {
+			return err
+		}
+	}
+	if h.size > 0 && h.size+int64(len(line)) > h.maxSize {
+		if err := h.rotate(); err != nil {
+			h.file = nil
+			return err
+		}
+	}
+	n, err := h.file.Write(line)
+	h.size += int64(n)
+	return err
+}
@@ -10,7 +10,6 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -23,14 +22,18 @@ func basicAuth(username, password string) string {
 	return authString
 }
 
-func (a *App) apiRequest(method, url string, data io.Reader) ([]byte, int) {
+func (a *App) apiRequest(method, url string, data io.Reader) ([]byte, int, error) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		IdleConnTimeout: 1 * time.Second,
 	}
 	req, err := http.NewRequest(method, url, data)
 	if err != nil {
-		log.Fatal(err)
+		return nil, 0, err
+	}
+	if a.ctx != nil {
+		// abandon the request when the runbook stage times out or is interrupted
+		req = req.WithContext(a.ctx)
 	}
 	authString := basicAuth(a.Config.Username, a.Config.Password)
 	req.Header.Set("Authorization", authString)
@@ -39,13 +42,13 @@ func (a *App) apiRequest(method, url string, data io.Reader) ([]byte, int) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatal(err)
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, 0, err
 	}
 
 	// log.WithFields(log.Fields{
@@ -54,47 +57,195 @@ func (a *App) apiRequest(method, url string, data io.Reader) ([]byte, int) {
 	// 	"body":       string(body),
 	// }).Debug(url)
 
-	return body, resp.StatusCode
+	return body, resp.StatusCode, nil
 }
 
-func (a *App) getAllGroups() []GroupUID {
-	endpoint := a.Config.RPAURL + "/fapi/rest/5_1/groups/"
-	body, _ := a.apiRequest("GET", endpoint, nil)
+// sleep pauses between polls, returning early with an error when the runbook stage is done
+func (a *App) sleep(d time.Duration) error {
+	if a.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-a.ctx.Done():
+		return a.ctx.Err()
+	}
+}
 
+// apiGet requests an api endpoint & decodes the json response into v
+func (a *App) apiGet(endpoint string, v interface{}) error {
+	body, statusCode, err := a.apiRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if statusCode != 200 {
+		return fmt.Errorf("GET %s: expected status code '200' and received: %d", endpoint, statusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+func (a *App) getAllGroups() ([]GroupUID, error) {
+	endpoint := a.Config.RPAURL + "/fapi/rest/5_1/groups/"
 	var gResp GroupsResponse
-	json.Unmarshal(body, &gResp)
-	return gResp.InnerSet
+	err := a.apiGet(endpoint, &gResp)
+	return gResp.InnerSet, err
 }
 
-func (a *App) getGroupName(groupID int) string {
+func (a *App) getGroupName(groupID int) (string, error) {
 	endpoint := fmt.Sprintf(a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/name/", groupID)
-	body, _ := a.apiRequest("GET", endpoint, nil)
-
 	var groupName GroupName
-	json.Unmarshal(body, &groupName)
-	return groupName.String
+	err := a.apiGet(endpoint, &groupName)
+	return groupName.String, err
 }
 
-func (a *App) getGroupIDByName(groupName string) int {
-	var id int
-	allGroups := a.getAllGroups()
+func (a *App) getGroupIDByName(groupName string) (int, error) {
+	allGroups, err := a.getAllGroups()
+	if err != nil {
+		return 0, err
+	}
 	for _, g := range allGroups {
-		n := a.getGroupName(g.ID)
+		n, err := a.getGroupName(g.ID)
+		if err != nil {
+			return 0, err
+		}
 		if groupName == n {
-			id = g.ID
+			return g.ID, nil
 		}
 	}
-	return id
+	return 0, fmt.Errorf("consistency group not found: %s", groupName)
 }
 
-func (a *App) getGroupCopiesSettings(groupID int) []GroupCopiesSettings {
+func (a *App) getGroupSettings(groupID int) (GroupSettingsResponse, error) {
 	endpoint := fmt.Sprintf(a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/settings/", groupID)
-	body, _ := a.apiRequest("GET", endpoint, nil)
-
 	var gsr GroupSettingsResponse
-	json.Unmarshal(body, &gsr)
+	err := a.apiGet(endpoint, &gsr)
+	return gsr, err
+}
+
+func (a *App) getGroupCopiesSettings(groupID int) ([]GroupCopiesSettings, error) {
+	gsr, err := a.getGroupSettings(groupID)
+	if err != nil {
+		return nil, err
+	}
 	result := a.sortGroupCopies(gsr.GroupCopiesSettings)
-	return result
+	return result, nil
+}
+
+func (a *App) getGroupState(groupID int) (GroupStateResponse, error) {
+	endpoint := fmt.Sprintf(a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/state/", groupID)
+	var gsr GroupStateResponse
+	err := a.apiGet(endpoint, &gsr)
+	return gsr, err
+}
+
+func (a *App) getGroupStatistics(groupID int) (GroupStatisticsResponse, error) {
+	endpoint := fmt.Sprintf(a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/statistics/", groupID)
+	var gsr GroupStatisticsResponse
+	err := a.apiGet(endpoint, &gsr)
+	return gsr, err
+}
+
+// getGroupCopyDetails combines the settings, state & statistics endpoints into a CopyDetail per copy
+func (a *App) getGroupCopyDetails(groupID int, groupName string) ([]CopyDetail, error) {
+	settings, err := a.getGroupSettings(groupID)
+	if err != nil {
+		return nil, err
+	}
+	state, err := a.getGroupState(groupID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := a.getGroupStatistics(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var details []CopyDetail
+	for _, cs := range a.sortGroupCopies(settings.GroupCopiesSettings) {
+		uid := cs.CopyUID.GlobalCopyUID
+		d := CopyDetail{
+			GroupName:          groupName,
+			Name:               cs.Name,
+			ClusterUID:         uid.ClusterUID.ID,
+			CopyUID:            uid.CopyUID,
+			Role:               cs.RoleInfo.Role,
+			ImageAccessEnabled: cs.ImageAccessInformation.ImageAccessEnabled,
+			ImageAccessMode:    cs.ImageAccessInformation.ImageInformation.Mode,
+		}
+		// link settings & statistics are keyed by the link to the copy (the second copy of a link)
+		for _, ls := range settings.ActiveLinksSettings {
+			if ls.GroupLinkUID.SecondCopy == uid {
+				d.ProtectionType = ls.LinkPolicy.ProtectionPolicy.ProtectionType
+				d.CompressionLevel = ls.LinkPolicy.AdvancedPolicy.CompressionLevel
+				d.ConfiguredRPO = quantityDuration(ls.LinkPolicy.ProtectionPolicy.RPOPolicy.MaximumAllowedLag)
+			}
+		}
+		for _, ls := range state.LinksState {
+			if ls.GroupLinkUID.SecondCopy == uid {
+				d.TransferState = ls.PipeState
+			}
+		}
+		for _, ls := range stats.ConsistencyGroupLinkStatistics {
+			if ls.GroupLinkUID.SecondCopy == uid {
+				d.CurrentRPO = time.Duration(ls.ProtectionStatistics.Lag.TimeCounter) * time.Microsecond
+			}
+		}
+		for _, cst := range state.GroupCopiesState {
+			if cst.CopyUID.GlobalCopyUID == uid {
+				d.StorageAccessState = cst.StorageAccessState
+				ts := cst.AccessedImage.ClosingTimeStamp.TimeInMicroSeconds
+				if ts > 0 {
+					d.ImageTimestamp = time.Unix(0, ts*int64(time.Microsecond))
+				}
+			}
+		}
+		for _, cst := range stats.ConsistencyGroupCopyStatistics {
+			if cst.CopyUID.GlobalCopyUID == uid {
+				js := cst.JournalStatistics
+				d.JournalUsedBytes = js.ActualJournalUsageInBytes
+				d.JournalSizeBytes = js.JournalCapacityInBytes
+				d.DistributionLag = js.JournalLagInBytes
+				if js.JournalCapacityInBytes > 0 {
+					d.JournalUsage = float64(js.ActualJournalUsageInBytes) / float64(js.JournalCapacityInBytes) * 100
+				}
+			}
+		}
+		details = append(details, d)
+	}
+	return details, nil
+}
+
+// quantityDuration converts a time based api quantity to a duration (non-time quantities return 0)
+func quantityDuration(q Quantity) time.Duration {
+	switch q.Type {
+	case "MICROSECONDS":
+		return time.Duration(q.Value) * time.Microsecond
+	case "MILLISECONDS":
+		return time.Duration(q.Value) * time.Millisecond
+	case "SECONDS":
+		return time.Duration(q.Value) * time.Second
+	case "MINUTES":
+		return time.Duration(q.Value) * time.Minute
+	case "HOURS":
+		return time.Duration(q.Value) * time.Hour
+	}
+	return 0
+}
+
+// formatBytes returns a human readable size for a number of bytes
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
 }
 
 func (a *App) sortGroupCopies(gcs []GroupCopiesSettings) []GroupCopiesSettings {
@@ -124,38 +275,110 @@ func (a *App) sortGroupCopies(gcs []GroupCopiesSettings) []GroupCopiesSettings {
 
 // ListGroups lists all consistency group names
 func (a *App) ListGroups() {
-	groups := a.getAllGroups()
-	for _, g := range groups {
-		name := a.getGroupName(g.ID)
+	names, err := a.getAllGroupNames()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range names {
 		fmt.Println(name) // consisntency group name
 	}
 }
 
 // DisplayAllGroups displays the status of all consisntenct groups
 func (a *App) DisplayAllGroups() {
-	groups := a.getAllGroups()
+	groups, err := a.getAllGroups()
+	if err != nil {
+		log.Fatal(err)
+	}
+	groupNames, err := a.getGroupNames(groups)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var groupIDs []int
 	for _, g := range groups {
-		name := a.getGroupName(g.ID)
-		fmt.Println(name) // consisntency group name
-		copySettings := a.getGroupCopiesSettings(g.ID)
-		for _, cs := range copySettings {
-			fmt.Printf("\t%s (%s)\n", cs.Name, cs.RoleInfo.Role)
-		}
+		groupIDs = append(groupIDs, g.ID)
+	}
+	journals, err := a.estimateJournals(groupIDs, a.Detail)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, g := range groups {
+		fmt.Println(groupNames[i]) // consisntency group name
+		a.displayCopies(g.ID, groupNames[i], journals[g.ID])
 	}
 }
 
 // DisplayGroup displays the status of a consistency group by group name
 func (a *App) DisplayGroup(groupName string) {
-	groupID := a.getGroupIDByName(groupName)
+	groupID, err := a.getGroupIDByName(groupName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	journals, err := a.estimateJournals([]int{groupID}, a.Detail)
+	if err != nil {
+		log.Fatal(err)
+	}
 	fmt.Println(groupName) // consisntency group name
-	copySettings := a.getGroupCopiesSettings(groupID)
-	for _, cs := range copySettings {
-		fmt.Printf("\t%s (%s)\n", cs.Name, cs.RoleInfo.Role)
+	a.displayCopies(groupID, groupName, journals[groupID])
+}
+
+// displayCopies displays the role of each group copy, including state & statistics when Detail is set.
+// Journal warnings & the estimated time until the journal is full (for copies in image access, when Detail
+// is set) are displayed from the provided journal estimates.
+func (a *App) displayCopies(groupID int, groupName string, journals journalEstimates) {
+	displayJournal := func(uid GlobalCopyUID, imageAccess bool) {
+		e, ok := journals[uid]
+		if !ok {
+			return
+		}
+		if w := a.journalWarning(e); w != "" {
+			fmt.Printf("\t\t%s\n", w)
+		}
+		if imageAccess && a.Detail && a.Config.JournalSampleInterval > 0 {
+			fmt.Printf("\t\t%s\n", describeJournalFull(e))
+		}
+	}
+	if !a.Detail {
+		copySettings, err := a.getGroupCopiesSettings(groupID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, cs := range copySettings {
+			fmt.Printf("\t%s (%s)\n", cs.Name, cs.RoleInfo.Role)
+			displayJournal(cs.CopyUID.GlobalCopyUID, cs.ImageAccessInformation.ImageAccessEnabled)
+		}
+		return
+	}
+	details, err := a.getGroupCopyDetails(groupID, groupName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range details {
+		fmt.Printf("\t%s (%s)\n", d.Name, d.Role)
+		if d.TransferState != "" {
+			fmt.Printf("\t\ttransfer state:    %s\n", d.TransferState)
+			fmt.Printf("\t\tlink policy:       %s (compression: %s)\n", d.ProtectionType, d.CompressionLevel)
+		}
+		imageAccess := "disabled"
+		if d.ImageAccessEnabled {
+			imageAccess = fmt.Sprintf("%s (%s)", d.ImageAccessMode, d.StorageAccessState)
+		}
+		fmt.Printf("\t\timage access:      %s\n", imageAccess)
+		if !d.ImageTimestamp.IsZero() {
+			fmt.Printf("\t\timage timestamp:   %s\n", d.ImageTimestamp.Format("2006-01-02 15:04:05 MST"))
+		}
+		fmt.Printf("\t\tjournal usage:     %.1f%% (%s of %s)\n",
+			d.JournalUsage, formatBytes(d.JournalUsedBytes), formatBytes(d.JournalSizeBytes))
+		fmt.Printf("\t\tdistribution lag:  %s\n", formatBytes(d.DistributionLag))
+		if d.TransferState != "" {
+			fmt.Printf("\t\trpo (current/max): %s / %s\n", d.CurrentRPO, d.ConfiguredRPO)
+		}
+		displayJournal(GlobalCopyUID{CopyUID: d.CopyUID, ClusterUID: ClusterUID{ID: d.ClusterUID}}, d.ImageAccessEnabled)
 	}
 }
 
-// getRequestedCopy attempts to determine the desired copy based on identifier prefixes and flags
-func (a *App) getRequestedCopy(gcs []GroupCopiesSettings) GroupCopiesSettings {
+// findRequestedCopy attempts to determine the desired copy based on identifier prefixes and flags
+func (a *App) findRequestedCopy(gcs []GroupCopiesSettings) (GroupCopiesSettings, bool) {
 	var c GroupCopiesSettings
 	for _, cs := range gcs {
 		// always skip the production node
@@ -185,9 +408,15 @@ func (a *App) getRequestedCopy(gcs []GroupCopiesSettings) GroupCopiesSettings {
 			c = cs
 		}
 	}
-	// when the struct is empty, provide user with valid copies for the consistency group
-	if c == (GroupCopiesSettings{}) {
-		log.Error("Unable to determine the desired copy to enable direct image access mode")
+	return c, c != (GroupCopiesSettings{})
+}
+
+// getRequestedCopy attempts to determine the desired copy based on identifier prefixes and flags,
+// listing the valid copies of the consistency group when the copy was not found
+func (a *App) getRequestedCopy(gcs []GroupCopiesSettings) (GroupCopiesSettings, error) {
+	c, ok := a.findRequestedCopy(gcs)
+	// when the copy was not found, provide user with valid copies for the consistency group
+	if !ok {
 		if a.CopyName != "" {
 			fmt.Println("Requested Copy: ", a.CopyName)
 		} else {
@@ -201,9 +430,18 @@ func (a *App) getRequestedCopy(gcs []GroupCopiesSettings) GroupCopiesSettings {
 			}
 			fmt.Println(" - ", cs.Name)
 		}
-		os.Exit(1)
+		return c, errors.New("unable to determine the desired copy to enable direct image access mode")
 	}
-	return c
+	return c, nil
+}
+
+// getGroupRequestedCopy returns the settings of the requested copy of a consistency group
+func (a *App) getGroupRequestedCopy(groupID int) (GroupCopiesSettings, error) {
+	groupCopiesSettings, err := a.getGroupCopiesSettings(groupID)
+	if err != nil {
+		return GroupCopiesSettings{}, err
+	}
+	return a.getRequestedCopy(groupCopiesSettings)
 }
 
 func (a *App) startTransfer(t Task) error {
@@ -211,23 +449,28 @@ func (a *App) startTransfer(t Task) error {
 		a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/clusters/%d/copies/%d/start_transfer",
 		t.GroupUID, t.ClusterUID, t.CopyUID)
 	if !a.Config.CheckMode {
-		body, statusCode := a.apiRequest("PUT", endpoint, nil)
+		body, statusCode, err := a.auditedRequest(t, "start_transfer", "PUT", endpoint, nil)
+		if err != nil {
+			return err
+		}
 		if statusCode != 204 {
-			log.Debugf("Expected status code '204' and received: %d\n", statusCode)
-			log.Warnf("%s - Error Starting Transfer for Copy %s\n", t.GroupName, t.CopyName)
+			a.logger(t.GroupName, t.CopyName).Debugf("Expected status code '204' and received: %d\n", statusCode)
+			a.logger(t.GroupName, t.CopyName).Warnf("%s - Error Starting Transfer for Copy %s\n", t.GroupName, t.CopyName)
 			return errors.New(string(body))
 		}
 	}
-	fmt.Printf("%s - Starting Transfer for Copy %s\n", t.GroupName, t.CopyName)
+	a.logger(t.GroupName, t.CopyName).Infof("%s - Starting Transfer for Copy %s", t.GroupName, t.CopyName)
 	return nil
 }
 
 func (a *App) imageAccess(t Task) error {
 	operationName := "Disabled"
 	operation := "disable_image_access"
+	action := "disable_image_access"
 	if t.Enable == true {
 		operationName = "Enabled"
 		operation = "image_access/latest/enable"
+		action = "enable_image_access"
 	}
 	endpoint := fmt.Sprintf(
 		a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/clusters/%d/copies/%d/%s",
@@ -239,36 +482,70 @@ func (a *App) imageAccess(t Task) error {
 
 	json, err := json.Marshal(&d)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	if !a.Config.CheckMode {
+		body, statusCode, err := a.auditedRequest(t, action, "PUT", endpoint, bytes.NewBuffer(json))
+		if err != nil {
+			return err
+		}
+		if statusCode != 204 {
+			a.logger(t.GroupName, t.CopyName).Debugf("Expected status code '204' and received: %d\n", statusCode)
+			return errors.New(string(body))
+		}
+	}
+	a.logger(t.GroupName, t.CopyName).Infof("%s - %s Latest Image for Group Copy %s", t.GroupName, operationName, t.CopyName)
+	return nil
+}
+
+func (a *App) createBookmark(t Task, name string) error {
+	endpoint := fmt.Sprintf(a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/create_bookmark", t.GroupUID)
+
+	var d BookmarkPutData
+	d.BookmarkName = name
+	d.ConsolidationPolicy = "NEVER_CONSOLIDATE"
+
+	json, err := json.Marshal(&d)
+	if err != nil {
+		return err
 	}
 
 	if !a.Config.CheckMode {
-		body, statusCode := a.apiRequest("PUT", endpoint, bytes.NewBuffer(json))
+		body, statusCode, err := a.auditedRequest(t, "create_bookmark", "PUT", endpoint, bytes.NewBuffer(json))
+		if err != nil {
+			return err
+		}
 		if statusCode != 204 {
-			log.Debugf("Expected status code '204' and received: %d\n", statusCode)
+			a.logger(t.GroupName, t.CopyName).Debugf("Expected status code '204' and received: %d\n", statusCode)
 			return errors.New(string(body))
 		}
 	}
-	fmt.Printf("%s - %s Latest Image for Group Copy %s\n", t.GroupName, operationName, t.CopyName)
+	a.logger(t.GroupName, t.CopyName).Infof("%s - Created Bookmark %s", t.GroupName, name)
 	return nil
 }
 
-func (a *App) pollImageAccessEnabled(groupID int, groupName string, stateDesired bool) {
+func (a *App) pollImageAccessEnabled(groupID int, groupName string, stateDesired bool) error {
 	pollDelay := a.Config.PollDelay // seconds
 	pollMax := a.Config.PollMax     // max times to poll before breaking the poll loop
 	pollCount := 0                  // iteration counter
 
 	fmt.Printf("%s - Waiting for image access to update..\n", groupName)
-	groupCopiesSettings := a.getGroupCopiesSettings(groupID)
-	copySettings := a.getRequestedCopy(groupCopiesSettings)
+	copySettings, err := a.getGroupRequestedCopy(groupID)
+	if err != nil {
+		return err
+	}
 	for copySettings.ImageAccessInformation.ImageAccessEnabled != stateDesired {
-		log.Debug("polling - image access enabled: ", copySettings.ImageAccessInformation.ImageAccessEnabled)
-		time.Sleep(time.Duration(pollDelay) * time.Second)
-		groupCopiesSettings = a.getGroupCopiesSettings(groupID)
-		copySettings = a.getRequestedCopy(groupCopiesSettings)
+		a.logger(groupName, "").Debug("polling - image access enabled: ", copySettings.ImageAccessInformation.ImageAccessEnabled)
+		if err := a.sleep(time.Duration(pollDelay) * time.Second); err != nil {
+			return err
+		}
+		copySettings, err = a.getGroupRequestedCopy(groupID)
+		if err != nil {
+			return err
+		}
 		if pollCount > pollMax {
-			fmt.Println("Maximum poll count reached while waiting for image access. Consider increasing 'pollmax' in configuration")
-			break
+			return errors.New("maximum poll count reached while waiting for image access, consider increasing 'pollmax' in configuration")
 		}
 		pollCount++
 	}
@@ -277,19 +554,23 @@ func (a *App) pollImageAccessEnabled(groupID int, groupName string, stateDesired
 		// if the desired state is to have image access == true, we should ensure that logged access is also
 		// set before continuing. This seems to take a few seconds longer.. so we will continue polling for mode.
 		for copySettings.ImageAccessInformation.ImageInformation.Mode != "LOGGED_ACCESS" {
-			log.Debug("polling image logged access mode: ", copySettings.ImageAccessInformation.ImageInformation.Mode)
-			time.Sleep(time.Duration(pollDelay) * time.Second)
-			groupCopiesSettings = a.getGroupCopiesSettings(groupID)
-			copySettings = a.getRequestedCopy(groupCopiesSettings)
+			a.logger(groupName, "").Debug("polling image logged access mode: ", copySettings.ImageAccessInformation.ImageInformation.Mode)
+			if err := a.sleep(time.Duration(pollDelay) * time.Second); err != nil {
+				return err
+			}
+			copySettings, err = a.getGroupRequestedCopy(groupID)
+			if err != nil {
+				return err
+			}
 			if pollCount > pollMax {
-				fmt.Println("Maximum poll count reached while waiting for logged access. Consider increasing 'pollmax' in configuration")
-				break
+				return errors.New("maximum poll count reached while waiting for logged access, consider increasing 'pollmax' in configuration")
 			}
 			pollCount++
 		}
 	}
-	log.Debug("polling complete - current image access enabled: ", copySettings.ImageAccessInformation.ImageAccessEnabled)
-	log.Debug("polling complete - current image logged access mode: ", copySettings.ImageAccessInformation.ImageInformation.Mode)
+	a.logger(groupName, "").Debug("polling complete - current image access enabled: ", copySettings.ImageAccessInformation.ImageAccessEnabled)
+	a.logger(groupName, "").Debug("polling complete - current image logged access mode: ", copySettings.ImageAccessInformation.ImageInformation.Mode)
+	return nil
 }
 
 func (a *App) directAccess(t Task) error {
@@ -307,156 +588,335 @@ func (a *App) directAccess(t Task) error {
 		a.Config.RPAURL+"/fapi/rest/5_1/groups/%d/clusters/%d/copies/%d/%s",
 		t.GroupUID, t.ClusterUID, t.CopyUID, operation)
 	if !a.Config.CheckMode {
-		body, statusCode := a.apiRequest("PUT", endpoint, nil)
+		body, statusCode, err := a.auditedRequest(t, operation, "PUT", endpoint, nil)
 		for statusCode != 204 {
-			log.Debugf("Expected status code '204' and received: %d\n", statusCode)
-			time.Sleep(time.Duration(pollDelay) * time.Second)
-			body, statusCode = a.apiRequest("PUT", endpoint, nil)
+			if err != nil {
+				return err
+			}
+			a.logger(t.GroupName, t.CopyName).Debugf("Expected status code '204' and received: %d\n", statusCode)
+			if err := a.sleep(time.Duration(pollDelay) * time.Second); err != nil {
+				return err
+			}
+			body, statusCode, err = a.auditedRequest(t, operation, "PUT", endpoint, nil)
 			if pollCount > pollMax {
-				log.Warnf("%s - Maximum poll count reached while waiting for direct access\n", t.GroupName)
-				log.Warnf("%s - Error %sing Direct Access for Copy %s\n", t.GroupName, operationName, t.CopyName)
+				a.logger(t.GroupName, t.CopyName).Warnf("%s - Maximum poll count reached while waiting for direct access\n", t.GroupName)
+				a.logger(t.GroupName, t.CopyName).Warnf("%s - Error %sing Direct Access for Copy %s\n", t.GroupName, operationName, t.CopyName)
 				return errors.New(string(body))
 			}
 			pollCount++
 		}
 	}
-	fmt.Printf("%s - %sed Direct Access for Copy %s\n", t.GroupName, operationName, t.CopyName)
+	a.logger(t.GroupName, t.CopyName).Infof("%s - %sed Direct Access for Copy %s", t.GroupName, operationName, t.CopyName)
 	return nil
 }
 
-// EnableAll wrapper for enabling Direct Image Access for all CG
-func (a *App) EnableAll() {
-	start := time.Now()
-	groups := a.getAllGroups()
+// newTask populates a task for the provided copy of a consistency group
+func newTask(groupName string, copySettings GroupCopiesSettings, enable bool) Task {
+	var t Task
+	t.GroupName = groupName
+	t.GroupUID = copySettings.CopyUID.GroupUID.ID
+	t.ClusterUID = copySettings.CopyUID.GlobalCopyUID.ClusterUID.ID
+	t.CopyName = copySettings.Name
+	t.CopyUID = copySettings.CopyUID.GlobalCopyUID.CopyUID
+	t.Enable = enable // whether to enable or disable the following tasks
+	return t
+}
+
+// enableGroup enables image access & direct access for the requested copy of a single CG
+func (a *App) enableGroup(groupID int, groupName string) (err error) {
+	t := Task{GroupName: groupName, Enable: true}
+	skipped := false // the copy was already active
+	defer func() {
+		if r := recover(); r != nil {
+			// record the group as failed rather than done before the run is aborted
+			a.completeGroup(groupName, t.CopyName, fmt.Errorf("%v", r))
+			panic(r)
+		}
+		a.completeGroup(groupName, t.CopyName, err)
+		// only lease copies put in direct access by this run (not copies which were already active)
+		if err == nil && a.changedByRun(groupName) {
+			a.addLease(groupName, t.CopyName)
+		}
+		if !skipped {
+			a.notifyGroup(t, err)
+		}
+	}()
+	copySettings, err := a.getGroupRequestedCopy(groupID)
+	if err != nil {
+		return err
+	}
+	t = newTask(groupName, copySettings, true)
+	a.recordState(groupID, groupName, t.CopyName)
+	// skip if copy is already 'enabled'
+	if copySettings.RoleInfo.Role == "ACTIVE" {
+		a.logger(groupName, copySettings.Name).Infof("%s - Image Access already enabled for copy: %s", groupName, copySettings.Name)
+		skipped = true
+		return nil
+	}
+	if err := a.checkWindow(groupName); err != nil {
+		return err
+	}
+	if err := a.checkJournalCapacity(groupID, t); err != nil {
+		return err
+	}
+	if a.Config.CheckMode {
+		return nil
+	}
+	a.startOperation(groupName, "enable", t.CopyName)
+	return a.runOperation("enable", t, func() error {
+		err := a.step(groupName, "image_access", func() error {
+			return a.imageAccess(t)
+		})
+		if err != nil {
+			return err
+		}
+		err = a.step(groupName, "poll_image_access_enabled", func() error {
+			return a.pollImageAccessEnabled(groupID, groupName, true)
+		})
+		if err != nil {
+			return err
+		}
+		return a.step(groupName, "direct_access", func() error {
+			return a.directAccess(t)
+		})
+	})
+}
+
+// finishGroup disables image access & starts transfer for the requested copy of a single CG
+func (a *App) finishGroup(groupID int, groupName string) (err error) {
+	t := Task{GroupName: groupName}
+	defer func() {
+		if r := recover(); r != nil {
+			// record the group as failed rather than done before the run is aborted
+			a.completeGroup(groupName, t.CopyName, fmt.Errorf("%v", r))
+			panic(r)
+		}
+		a.completeGroup(groupName, t.CopyName, err)
+		if err == nil {
+			a.removeLease(groupName, t.CopyName)
+		}
+		a.notifyGroup(t, err)
+	}()
+	copySettings, err := a.getGroupRequestedCopy(groupID)
+	if err != nil {
+		return err
+	}
+	t = newTask(groupName, copySettings, false)
+	a.recordState(groupID, groupName, t.CopyName)
+	if a.Config.CheckMode {
+		return nil
+	}
+	a.startOperation(groupName, "finish", t.CopyName)
+	return a.runOperation("finish", t, func() error {
+		err := a.step(groupName, "disable_image_access", func() error {
+			return a.imageAccess(t)
+		})
+		if err != nil {
+			// return as we cannot start transfer when image access does
+			// not update as expected.
+			return err
+		}
+		err = a.step(groupName, "poll_image_access_disabled", func() error {
+			return a.pollImageAccessEnabled(groupID, groupName, false)
+		})
+		if err != nil {
+			return err
+		}
+		return a.step(groupName, "start_transfer", func() error {
+			return a.startTransfer(t)
+		})
+	})
+}
+
+// getGroupNames returns the names of the provided groups
+func (a *App) getGroupNames(groups []GroupUID) ([]string, error) {
+	var names []string
 	for _, g := range groups {
-		var t Task
-		groupName := a.getGroupName(g.ID)
-		groupCopiesSettings := a.getGroupCopiesSettings(g.ID)
-		copySettings := a.getRequestedCopy(groupCopiesSettings)
-		// skip if copy is already 'enabled'
-		if copySettings.RoleInfo.Role == "ACTIVE" {
-			fmt.Printf("%s - Image Access already enabled for copy: %s\n", a.Group, copySettings.Name)
-			continue
+		name, err := a.getGroupName(g.ID)
+		if err != nil {
+			return nil, err
 		}
-		t.GroupName = groupName
-		t.GroupUID = copySettings.CopyUID.GroupUID.ID
-		t.ClusterUID = copySettings.CopyUID.GlobalCopyUID.ClusterUID.ID
-		t.CopyName = copySettings.Name
-		t.CopyUID = copySettings.CopyUID.GlobalCopyUID.CopyUID
-		t.Enable = true // whether to enable or disable the following tasks
-		if !a.Config.CheckMode {
-			err := a.imageAccess(t)
-			if err != nil {
-				log.Warnf("%s - %s\n", groupName, err)
-				continue
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// getAllGroupNames returns the names of all consistency groups
+func (a *App) getAllGroupNames() ([]string, error) {
+	groups, err := a.getAllGroups()
+	if err != nil {
+		return nil, err
+	}
+	return a.getGroupNames(groups)
+}
+
+// EnableAll wrapper for enabling Direct Image Access for all CG.
+// With Canary set, the first Canary groups are verified before continuing with the remaining groups.
+// The run stops once MaxFailures groups have failed (or on the first failure when RollbackOnFailure
+// is set without MaxFailures), after which the groups enabled by the run are finished when
+// RollbackOnFailure is set.
+func (a *App) EnableAll() error {
+	start := time.Now()
+	var groupNames []string
+	groups, err := a.getAllGroups()
+	if err == nil {
+		groupNames, err = a.getGroupNames(groups)
+	}
+	if err != nil {
+		a.logger("", "").Error(err)
+		return err
+	}
+	owner := a.beginRun("enable", groupNames)
+	defer a.endRun(owner)
+
+	maxFailures := a.MaxFailures
+	if maxFailures == 0 && a.RollbackOnFailure {
+		maxFailures = 1
+	}
+	failures := 0
+	for i, g := range groups {
+		groupName := groupNames[i]
+		err := a.enableGroup(g.ID, groupName)
+		if err != nil {
+			a.logger(groupName, "").Warnf("%s - %s\n", groupName, err)
+			failures++
+			if maxFailures > 0 && failures >= maxFailures {
+				a.logger("", "").Errorf("Stopping after %d failed consistency groups", failures)
+				if a.RollbackOnFailure {
+					a.rollbackEnabled(groups[:i+1], groupNames[:i+1])
+				}
+				return fmt.Errorf("enable stopped after %d failed consistency groups", failures)
 			}
-			a.pollImageAccessEnabled(g.ID, groupName, true)
-			err = a.directAccess(t)
+		}
+		if a.canaryReached(i, len(groups)) {
+			err = a.verifyCanary(true, groups[:i+1], groupNames[:i+1], len(groups)-i-1)
 			if err != nil {
-				log.Warnf("%s - %s\n", groupName, err)
-				continue
+				a.logger("", "").Error(err)
+				if a.RollbackOnFailure {
+					a.rollbackEnabled(groups[:i+1], groupNames[:i+1])
+				}
+				return err
 			}
+			continue
+		}
+		if err == nil {
+			time.Sleep(time.Duration(a.Config.Delay) * time.Second)
 		}
-		time.Sleep(time.Duration(a.Config.Delay) * time.Second)
 	}
 	elapsed := time.Since(start)
-	log.Printf("Done. (took %s)\n", elapsed)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	if failures > 0 {
+		return fmt.Errorf("%d consistency groups failed", failures)
+	}
+	return nil
 }
 
-// EnableOne wrapper for enabling Direct Image Access for a single CG
-func (a *App) EnableOne() {
-	start := time.Now()
-	groupID := a.getGroupIDByName(a.Group)
-	var t Task
-	groupCopiesSettings := a.getGroupCopiesSettings(groupID)
-	copySettings := a.getRequestedCopy(groupCopiesSettings)
-	// skip if copy is already 'enabled'
-	if copySettings.RoleInfo.Role == "ACTIVE" {
-		fmt.Printf("%s - Image Access already enabled for copy: %s\n", a.Group, copySettings.Name)
+// rollbackEnabled finishes (in reverse order) the groups which were changed by the current run
+// and reports the result of each rollback
+func (a *App) rollbackEnabled(groups []GroupUID, groupNames []string) {
+	var rollback []int
+	for i := len(groups) - 1; i >= 0; i-- {
+		if a.changedByRun(groupNames[i]) {
+			rollback = append(rollback, i)
+		}
+	}
+	if len(rollback) == 0 {
+		fmt.Println("No consistency groups were enabled by this run, nothing to roll back")
 		return
 	}
-	t.GroupName = a.Group
-	t.GroupUID = copySettings.CopyUID.GroupUID.ID
-	t.ClusterUID = copySettings.CopyUID.GlobalCopyUID.ClusterUID.ID
-	t.CopyName = copySettings.Name
-	t.CopyUID = copySettings.CopyUID.GlobalCopyUID.CopyUID
-	t.Enable = true // whether to enable or disable the following tasks
-	if !a.Config.CheckMode {
-		err := a.imageAccess(t)
-		if err != nil {
-			log.Warnf("%s - %s\n", a.Group, err)
-			return
-		}
-		a.pollImageAccessEnabled(groupID, a.Group, true)
-		err = a.directAccess(t)
-		if err != nil {
-			log.Warnf("%s - %s\n", a.Group, err)
+	a.logger("", "").Warnf("Rolling back %d consistency groups enabled by this run", len(rollback))
+	results := make(map[int]error)
+	for _, i := range rollback {
+		results[i] = a.finishGroup(groups[i].ID, groupNames[i])
+	}
+	fmt.Println("Rollback results:")
+	for _, i := range rollback {
+		if results[i] != nil {
+			fmt.Printf("%s - Rollback failed: %s\n", groupNames[i], results[i])
+			continue
 		}
+		fmt.Printf("%s - Rolled back\n", groupNames[i])
+	}
+}
+
+// EnableOne wrapper for enabling Direct Image Access for a single CG
+func (a *App) EnableOne() error {
+	start := time.Now()
+	owner := a.beginRun("enable", []string{a.Group})
+	defer a.endRun(owner)
+	groupID, err := a.getGroupIDByName(a.Group)
+	if err == nil {
+		err = a.enableGroup(groupID, a.Group)
+	} else {
+		a.completeGroup(a.Group, "", err)
+	}
+	if err != nil {
+		a.logger(a.Group, "").Warnf("%s - %s\n", a.Group, err)
+		return err
 	}
 	elapsed := time.Since(start)
-	log.Printf("Done. (took %s)\n", elapsed)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	return nil
 }
 
 // FinishAll wrapper for finishing Direct Image Access for all CG
-func (a *App) FinishAll() {
+func (a *App) FinishAll() error {
 	start := time.Now()
-	groups := a.getAllGroups()
-	for _, g := range groups {
-		var t Task
-		groupName := a.getGroupName(g.ID)
-		groupCopiesSettings := a.getGroupCopiesSettings(g.ID)
-		copySettings := a.getRequestedCopy(groupCopiesSettings)
-		t.GroupName = groupName
-		t.GroupUID = copySettings.CopyUID.GroupUID.ID
-		t.ClusterUID = copySettings.CopyUID.GlobalCopyUID.ClusterUID.ID
-		t.CopyName = copySettings.Name
-		t.CopyUID = copySettings.CopyUID.GlobalCopyUID.CopyUID
-		t.Enable = false // whether to enable or disable the following tasks
-		if !a.Config.CheckMode {
-			err := a.imageAccess(t)
-			if err != nil {
-				log.Warnf("%s - %s\n", groupName, err)
-				// continue as we cannot start transfer when image access does
-				// not update as expected.
-				continue
-			}
-			a.pollImageAccessEnabled(g.ID, groupName, false)
-			err = a.startTransfer(t)
+	var groupNames []string
+	groups, err := a.getAllGroups()
+	if err == nil {
+		groupNames, err = a.getGroupNames(groups)
+	}
+	if err != nil {
+		a.logger("", "").Error(err)
+		return err
+	}
+	owner := a.beginRun("finish", groupNames)
+	defer a.endRun(owner)
+	failures := 0
+	for i, g := range groups {
+		groupName := groupNames[i]
+		err := a.finishGroup(g.ID, groupName)
+		if err != nil {
+			a.logger(groupName, "").Warnf("%s - %s\n", groupName, err)
+			failures++
+		}
+		if a.canaryReached(i, len(groups)) {
+			err = a.verifyCanary(false, groups[:i+1], groupNames[:i+1], len(groups)-i-1)
 			if err != nil {
-				log.Warnf("%s - %s\n", groupName, err)
+				a.logger("", "").Error(err)
+				return err
 			}
+			continue
+		}
+		if err == nil {
+			time.Sleep(time.Duration(a.Config.Delay) * time.Second)
 		}
-		time.Sleep(time.Duration(a.Config.Delay) * time.Second)
 	}
 	elapsed := time.Since(start)
-	log.Printf("Done. (took %s)\n", elapsed)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	if failures > 0 {
+		return fmt.Errorf("%d consistency groups failed", failures)
+	}
+	return nil
 }
 
 // FinishOne wrapper for finishing Direct Image Access for a single CG
-func (a *App) FinishOne() {
+func (a *App) FinishOne() error {
 	start := time.Now()
-	groupID := a.getGroupIDByName(a.Group)
-	var t Task
-	groupCopiesSettings := a.getGroupCopiesSettings(groupID)
-	copySettings := a.getRequestedCopy(groupCopiesSettings)
-	t.GroupName = a.Group
-	t.GroupUID = copySettings.CopyUID.GroupUID.ID
-	t.ClusterUID = copySettings.CopyUID.GlobalCopyUID.ClusterUID.ID
-	t.CopyName = copySettings.Name
-	t.CopyUID = copySettings.CopyUID.GlobalCopyUID.CopyUID
-	t.Enable = false // whether to enable or disable the following tasks
-	if !a.Config.CheckMode {
-		err := a.imageAccess(t)
-		if err != nil {
-			log.Warnf("%s - %s\n", a.Group, err)
-			return
-		}
-		a.pollImageAccessEnabled(groupID, a.Group, false)
-		err = a.startTransfer(t)
-		if err != nil {
-			log.Warnf("%s - %s\n", a.Group, err)
-		}
+	owner := a.beginRun("finish", []string{a.Group})
+	defer a.endRun(owner)
+	groupID, err := a.getGroupIDByName(a.Group)
+	if err == nil {
+		err = a.finishGroup(groupID, a.Group)
+	} else {
+		a.completeGroup(a.Group, "", err)
+	}
+	if err != nil {
+		a.logger(a.Group, "").Warnf("%s - %s\n", a.Group, err)
+		return err
 	}
 	elapsed := time.Since(start)
-	log.Printf("Done. (took %s)\n", elapsed)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	return nil
 }
@@ -1,6 +1,12 @@
 package rpa
 
-import "regexp"
+import (
+	"context"
+	"regexp"
+	"sync"
+	"text/template"
+	"time"
+)
 
 // APPLICATION STATE & CONFIGURATION
 // =================================================================================================
@@ -12,6 +18,34 @@ type App struct {
 	CopyName    string         `json:"-"`
 	CopyRegexp  *regexp.Regexp `json:"-"`
 	Identifiers *Identifiers   `json:"identifiers"`
+	Detail      bool           `json:"-"`
+
+	RollbackOnFailure bool `json:"-"` // finish groups enabled by a bulk enable when it stops due to failures
+	MaxFailures       int  `json:"-"` // number of failed groups which stops a bulk enable (0: never stop)
+	Canary            int  `json:"-"` // number of groups verified before continuing a bulk operation
+	Yes               bool `json:"-"` // continue after canary verification without confirmation
+
+	Lease time.Duration `json:"-"` // duration of the lease recorded for copies enabled by this run
+
+	MaxJournalUsage float64 `json:"-"` // journal usage percent at which enable is refused (0: never refuse)
+
+	Report string `json:"-"` // path of the evidence report written when the run ends (.html, .md or .json)
+
+	JUnit string `json:"-"` // path of the junit xml results written when the run ends
+
+	ctx       context.Context // context of the runbook stage in progress, abandons api requests & polling when done
+	run       *Run
+	runMu     sync.Mutex
+	abortOnce sync.Once // registers abortRun as an exit handler
+	resuming  bool
+	leaseMu   sync.Mutex
+
+	reportBefore map[string]GroupSnapshot // state of each group before it was first changed by the run
+	reportImages map[string]time.Time     // image last accessed on the copy of each group changed by the run
+
+	notifyMu   sync.Mutex
+	events     chan Event    // events queued for delivery to the notifiers
+	notifyDone chan struct{} // closed once the queued events have been delivered
 }
 
 // Config contains various API configurations for the application
@@ -24,6 +58,138 @@ type Config struct {
 	PollMax   int    `json:"pollmax"`
 	CheckMode bool   `json:"-"`
 	Debug     bool   `json:"-"`
+
+	RPOMax     time.Duration            `json:"-"`
+	RPOWarning time.Duration            `json:"-"`
+	RPOGroups  map[string]time.Duration `json:"-"`
+
+	ExporterListen   string        `json:"-"`
+	ExporterInterval time.Duration `json:"-"`
+
+	Applications map[string][]ApplicationGroup `json:"-"`
+	Hooks        Hooks                         `json:"-"`
+	JournalPath  string                        `json:"-"`
+
+	JournalWarning        float64       `json:"-"` // copy journal usage percent to warn at
+	JournalSampleInterval time.Duration `json:"-"` // time between journal usage samples to estimate write rate
+
+	LeasesPath   string        `json:"-"`
+	LeaseWarning time.Duration `json:"-"`
+
+	Windows        []Window `json:"-"`
+	OverrideWindow bool     `json:"-"`
+	Reason         string   `json:"-"`
+
+	AuditPath     string `json:"-"`
+	Ticket        string `json:"-"`
+	RequireTicket bool   `json:"-"`
+
+	Webhooks []Webhook `json:"-"`
+	Email    Email     `json:"-"`
+}
+
+// ApplicationGroup is a consistency group of an application along with the groups it depends on
+type ApplicationGroup struct {
+	Group     string   `mapstructure:"group"`
+	DependsOn []string `mapstructure:"depends_on"`
+}
+
+// HookCommands are the external commands run before & after operations along with their timeout
+type HookCommands struct {
+	Timeout    time.Duration `mapstructure:"timeout"`
+	PreEnable  string        `mapstructure:"pre_enable"`
+	PostEnable string        `mapstructure:"post_enable"`
+	PreFinish  string        `mapstructure:"pre_finish"`
+	PostFinish string        `mapstructure:"post_finish"`
+	OnFailure  string        `mapstructure:"on_failure"`
+	Canary     string        `mapstructure:"canary"`
+}
+
+// Hooks holds the global hook commands & the hook commands for groups matching a regexp
+type Hooks struct {
+	HookCommands `mapstructure:",squash"`
+	Groups       []GroupHooks `mapstructure:"groups"`
+}
+
+// GroupHooks holds the hook commands for consistency groups with a name matching Regexp
+type GroupHooks struct {
+	HookCommands `mapstructure:",squash"`
+	Regexp       string         `mapstructure:"regexp"`
+	Pattern      *regexp.Regexp `mapstructure:"-"`
+}
+
+// Window is a maintenance window during which copies may be enabled. Windows with a Regexp only apply
+// to consistency groups with a matching name.
+type Window struct {
+	Name     string   `mapstructure:"name"`
+	Days     []string `mapstructure:"days"`     // mon, tue, wed, thu, fri, sat & sun (default: every day)
+	Start    string   `mapstructure:"start"`    // HH:MM
+	End      string   `mapstructure:"end"`      // HH:MM (before start when the window spans midnight)
+	TimeZone string   `mapstructure:"timezone"` // ie: Europe/London (default: local time)
+	Regexp   string   `mapstructure:"regexp"`
+
+	pattern  *regexp.Regexp
+	location *time.Location
+	weekdays []time.Weekday
+	start    int // minutes since midnight
+	end      int // minutes since midnight
+}
+
+// Webhook posts run lifecycle events to a url. The body is the event as json unless a template is
+// configured and is signed with HMAC-SHA256 when a secret is configured.
+type Webhook struct {
+	URL      string            `mapstructure:"url"`
+	Events   []string          `mapstructure:"events"` // events to post (default: all events)
+	Headers  map[string]string `mapstructure:"headers"`
+	Template string            `mapstructure:"template"` // go template of the body, executed with the event
+	Secret   string            `mapstructure:"secret"`
+	Retries  int               `mapstructure:"retries"` // attempts after a failed attempt (default: 3)
+	Timeout  time.Duration     `mapstructure:"timeout"` // of each attempt (default: 10s)
+
+	body *template.Template
+}
+
+// Email sends a report of each run of the configured commands to the recipients once the run finishes
+type Email struct {
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"`     // default: 25
+	StartTLS bool     `mapstructure:"starttls"` // require STARTTLS before authenticating & sending
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	Cc       []string `mapstructure:"cc"`
+	Commands []string `mapstructure:"commands"` // commands reported (default: enable & finish)
+}
+
+// Logging holds the logging backends used in addition to the console (see 'logging' in config)
+type Logging struct {
+	Format string    `mapstructure:"format"` // console format: text (default) or json
+	Syslog LogSyslog `mapstructure:"syslog"`
+	File   LogFile   `mapstructure:"file"`
+}
+
+// LogSyslog sends log entries to a syslog server as RFC 5424 messages
+type LogSyslog struct {
+	Network  string `mapstructure:"network"`  // udp, tcp or unix
+	Address  string `mapstructure:"address"`  // host:port or path of a unix socket (ie: /dev/log)
+	Facility string `mapstructure:"facility"` // ie: local0 (default: user)
+	Tag      string `mapstructure:"tag"`      // app-name of messages (default: rpda)
+}
+
+// LogFile writes log entries as json lines to a file which is rotated when it reaches MaxSize
+type LogFile struct {
+	Path       string `mapstructure:"path"`
+	MaxSize    int    `mapstructure:"max_size"`    // megabytes (default: 10)
+	MaxBackups int    `mapstructure:"max_backups"` // rotated files kept (default: 5)
+}
+
+// HookPayload is provided as json on stdin to hook commands
+type HookPayload struct {
+	Event string `json:"event"`
+	Task
+	CheckMode bool   `json:"check_mode"`
+	Error     string `json:"error,omitempty"`
 }
 
 // Identifiers describe the regular expression strings for use in copy name validations
@@ -36,12 +202,256 @@ type Identifiers struct {
 // Task is used to pass variables required to perform various tasks to the API
 // This helps avoid creating functions with multiple args and provides meaningful variable names
 type Task struct {
-	GroupName  string
-	GroupUID   int
-	ClusterUID int
-	CopyName   string
-	CopyUID    int
-	Enable     bool
+	GroupName  string `json:"group_name"`
+	GroupUID   int    `json:"group_uid"`
+	ClusterUID int    `json:"cluster_uid"`
+	CopyName   string `json:"copy_name"`
+	CopyUID    int    `json:"copy_uid"`
+	Enable     bool   `json:"enable"`
+}
+
+// CopyDetail combines the settings, state & statistics of a single group copy for display
+type CopyDetail struct {
+	GroupName          string
+	Name               string
+	ClusterUID         int
+	CopyUID            int
+	Role               string
+	TransferState      string
+	ProtectionType     string
+	CompressionLevel   string
+	ImageAccessEnabled bool
+	ImageAccessMode    string
+	StorageAccessState string
+	ImageTimestamp     time.Time
+	JournalUsage       float64 // percent
+	JournalUsedBytes   int64
+	JournalSizeBytes   int64
+	DistributionLag    int64 // bytes
+	CurrentRPO         time.Duration
+	ConfiguredRPO      time.Duration
+}
+
+// RUNBOOKS
+// =================================================================================================
+
+// Runbook describes the ordered stages of a DR drill executed by 'rpda run'
+type Runbook struct {
+	Name   string  `mapstructure:"name"`
+	Stages []Stage `mapstructure:"stages"`
+}
+
+// Stage describes the actions performed on a selection of consistency groups within a runbook
+type Stage struct {
+	Name        string        `mapstructure:"name"`
+	Groups      []string      `mapstructure:"groups"`
+	GroupRegexp string        `mapstructure:"group_regexp"`
+	Copy        string        `mapstructure:"copy"` // 'test', 'dr' or a copy name
+	Actions     []Action      `mapstructure:"actions"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	OnFailure   string        `mapstructure:"on_failure"` // 'abort' (default), 'continue' or 'rollback'
+}
+
+// Action describes a single step of a runbook stage
+type Action struct {
+	Action   string        `mapstructure:"action"`   // bookmark, enable, wait, exec, pause or finish
+	Name     string        `mapstructure:"name"`     // bookmark name
+	Duration time.Duration `mapstructure:"duration"` // time to wait
+	Command  []string      `mapstructure:"command"`  // command & arguments to exec
+	Message  string        `mapstructure:"message"`  // message displayed when paused
+}
+
+// OPERATION JOURNAL
+// =================================================================================================
+
+// Run records the progress of an invocation on each consistency group. The current run is persisted
+// to the journal file after every step so that an interrupted run can be resumed.
+type Run struct {
+	ID         string      `json:"id"`
+	Command    string      `json:"command"`
+	CopyName   string      `json:"copy_name,omitempty"`
+	CopyRegexp string      `json:"copy_regexp,omitempty"`
+	Started    time.Time   `json:"started"`
+	Finished   time.Time   `json:"finished"`
+	Completed  bool        `json:"completed"`
+	Reason     string      `json:"reason,omitempty"`
+	Ticket     string      `json:"ticket,omitempty"`
+	Groups     []*GroupRun `json:"groups"`
+}
+
+// GroupRun records the progress of a single consistency group within a run
+type GroupRun struct {
+	Name   string  `json:"name"`
+	Copy   string  `json:"copy,omitempty"`
+	Status string  `json:"status"` // pending, running, done or failed
+	Error  string  `json:"error,omitempty"`
+	Steps  []*Step `json:"steps"`
+
+	Operation     string `json:"operation,omitempty"` // operation in progress on the group (enable or finish)
+	OperationStep int    `json:"operation_step"`      // index of the first step of the operation
+
+	WindowOverride bool `json:"window_override,omitempty"` // enabled outside of a maintenance window
+}
+
+// Step records a single step (api call, poll or hook) performed on a consistency group
+type Step struct {
+	Name      string        `json:"name"`
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	Output    string        `json:"output,omitempty"`
+	Completed bool          `json:"completed"` // false while running, the step was interrupted when not completed by the end of a run
+}
+
+// NOTIFICATIONS
+// =================================================================================================
+
+// Notifier delivers the lifecycle events of runs (ie: webhooks & email)
+type Notifier interface {
+	Notify(e Event) error
+	String() string // describes the notifier in log messages
+}
+
+// Event is a lifecycle event of a run: run_started, group_enabled, group_finished, group_failed or
+// run_finished (with a summary of the run)
+type Event struct {
+	Event     string      `json:"event"`
+	Time      time.Time   `json:"time"`
+	RunID     string      `json:"run_id,omitempty"`
+	Command   string      `json:"command,omitempty"`
+	Operator  string      `json:"operator"`
+	Host      string      `json:"host"`
+	Ticket    string      `json:"ticket,omitempty"`
+	Reason    string      `json:"reason,omitempty"`
+	CheckMode bool        `json:"check_mode"`
+	Groups    []string    `json:"groups,omitempty"` // groups of the run known when it started
+	Group     string      `json:"group,omitempty"`
+	Copy      string      `json:"copy,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Summary   *RunSummary `json:"summary,omitempty"`
+}
+
+// RunSummary is the outcome of a finished run along with the result of each group
+type RunSummary struct {
+	Outcome   string        `json:"outcome"` // success or failure
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"duration"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Pending   int           `json:"pending"`           // groups which were not reached (ie: stopped after failures)
+	Aborted   bool          `json:"aborted,omitempty"` // the run was aborted by a fatal error
+	Groups    []*GroupRun   `json:"groups"`
+}
+
+// LEASES
+// =================================================================================================
+
+// Lease records a copy left in direct access until an expiry, after which it is finished by 'leases reap'
+type Lease struct {
+	Group   string    `json:"group"`
+	Copy    string    `json:"copy"`
+	Owner   string    `json:"owner"`
+	Host    string    `json:"host"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// AUDIT LOG
+// =================================================================================================
+
+// AuditRecord is a single line of the audit log recording a mutating api call, or a completed run
+// (action 'run') along with every step performed on each group
+type AuditRecord struct {
+	Time           time.Time     `json:"time"`
+	RunID          string        `json:"run_id,omitempty"`
+	OSUser         string        `json:"os_user"`
+	RPAUser        string        `json:"rpa_user"`
+	Host           string        `json:"host"`
+	CommandLine    string        `json:"command_line"`
+	Ticket         string        `json:"ticket,omitempty"`
+	Reason         string        `json:"reason,omitempty"`
+	WindowOverride bool          `json:"window_override,omitempty"`
+	Action         string        `json:"action"`
+	Group          string        `json:"group"`
+	Copy           string        `json:"copy,omitempty"`
+	Endpoint       string        `json:"endpoint"`
+	Method         string        `json:"method"`
+	HTTPStatus     int           `json:"http_status"`
+	Outcome        string        `json:"outcome"` // success or failure
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	Run            *Run          `json:"run,omitempty"`
+}
+
+// STATUS SNAPSHOTS
+// =================================================================================================
+
+// StatusSnapshot records the state of every copy of every consistency group at a point in time
+type StatusSnapshot struct {
+	Taken  time.Time       `json:"taken"`
+	Groups []GroupSnapshot `json:"groups"`
+}
+
+// GroupSnapshot records the state of the copies of a single consistency group
+type GroupSnapshot struct {
+	Name   string         `json:"name"`
+	Copies []CopySnapshot `json:"copies"`
+}
+
+// CopySnapshot records the state of a single group copy
+type CopySnapshot struct {
+	Name               string `json:"name"`
+	ClusterUID         int    `json:"cluster_uid"`
+	CopyUID            int    `json:"copy_uid"`
+	Role               string `json:"role"`
+	ImageAccessEnabled bool   `json:"image_access_enabled"`
+	ImageAccessMode    string `json:"image_access_mode,omitempty"`
+	StorageAccessState string `json:"storage_access_state,omitempty"`
+	TransferState      string `json:"transfer_state,omitempty"`
+
+	ImageTimestamp *time.Time `json:"image_timestamp,omitempty"` // closing time of the accessed image
+}
+
+// REPORTS
+// =================================================================================================
+
+// Report is the evidence of a run written by --report
+type Report struct {
+	Generated   time.Time     `json:"generated"`
+	RunID       string        `json:"run_id"`
+	Command     string        `json:"command"`
+	CommandLine string        `json:"command_line"`
+	Operator    string        `json:"operator"`
+	RPAUser     string        `json:"rpa_user"`
+	Host        string        `json:"host"`
+	Ticket      string        `json:"ticket,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+	CheckMode   bool          `json:"check_mode"`
+	Started     time.Time     `json:"started"`
+	Finished    time.Time     `json:"finished"`
+	Duration    time.Duration `json:"duration"`
+	Outcome     string        `json:"outcome"` // success or failure
+	Groups      []GroupReport `json:"groups"`
+}
+
+// GroupReport is the evidence of the operation on a single consistency group within a run
+type GroupReport struct {
+	Name           string        `json:"name"`
+	Copy           string        `json:"copy,omitempty"`
+	Status         string        `json:"status"`
+	Error          string        `json:"error,omitempty"`
+	WindowOverride bool          `json:"window_override,omitempty"`
+	Duration       time.Duration `json:"duration"`
+	Image          *time.Time    `json:"image,omitempty"` // closing time of the image accessed on the copy
+	Copies         []CopyReport  `json:"copies"`
+	Steps          []*Step       `json:"steps"`
+}
+
+// CopyReport is the state of a group copy before & after a run
+type CopyReport struct {
+	Name   string        `json:"name"`
+	Before *CopySnapshot `json:"before,omitempty"`
+	After  *CopySnapshot `json:"after,omitempty"`
 }
 
 // API RESPONSE DATA STRUCTURES
@@ -60,6 +470,19 @@ type GroupsResponse struct {
 // GroupSettingsResponse to marshal response from /fapi/rest/5_1/groups/{id}/settings/"
 type GroupSettingsResponse struct {
 	GroupCopiesSettings []GroupCopiesSettings `json:"groupCopiesSettings"`
+	ActiveLinksSettings []LinkSettings        `json:"activeLinksSettings"`
+}
+
+// GroupStateResponse to marshal response from /fapi/rest/5_1/groups/{id}/state/
+type GroupStateResponse struct {
+	GroupCopiesState []CopyState `json:"groupCopiesState"`
+	LinksState       []LinkState `json:"linksState"`
+}
+
+// GroupStatisticsResponse to marshal response from /fapi/rest/5_1/groups/{id}/statistics/
+type GroupStatisticsResponse struct {
+	ConsistencyGroupCopyStatistics []CopyStatistics `json:"consistencyGroupCopyStatistics"`
+	ConsistencyGroupLinkStatistics []LinkStatistics `json:"consistencyGroupLinkStatistics"`
 }
 
 // User is used by UsersSettingsResponse
@@ -119,6 +542,110 @@ type RoleInfo struct {
 	Role string `json:"role"`
 }
 
+// LinkSettings is used by GroupSettingsResponse for activeLinksSettings
+type LinkSettings struct {
+	GroupLinkUID GroupLinkUID `json:"groupLinkUID"`
+	LinkPolicy   LinkPolicy   `json:"linkPolicy"`
+}
+
+// GroupLinkUID identifies the link between two copies of a consistency group
+type GroupLinkUID struct {
+	GroupUID   GroupUID      `json:"groupUID"`
+	FirstCopy  GlobalCopyUID `json:"firstCopy"`
+	SecondCopy GlobalCopyUID `json:"secondCopy"`
+}
+
+// LinkPolicy holds the protection & compression policies of a link within activeLinksSettings
+type LinkPolicy struct {
+	ProtectionPolicy ProtectionPolicy `json:"protectionPolicy"`
+	AdvancedPolicy   AdvancedPolicy   `json:"advancedPolicy"`
+}
+
+// ProtectionPolicy holds the replication mode & rpo policy of a link
+type ProtectionPolicy struct {
+	ProtectionType string    `json:"protectionType"`
+	RPOPolicy      RPOPolicy `json:"rpoPolicy"`
+}
+
+// RPOPolicy holds the maximum lag allowed on a link before the rpo is considered missed
+type RPOPolicy struct {
+	MaximumAllowedLag Quantity `json:"maximumAllowedLag"`
+}
+
+// Quantity is a value & unit pair (ie: 25 SECONDS) used throughout the api
+type Quantity struct {
+	Value int64  `json:"value"`
+	Type  string `json:"type"`
+}
+
+// AdvancedPolicy holds the compression level of a link
+type AdvancedPolicy struct {
+	CompressionLevel string `json:"compressionLevel"`
+}
+
+// CopyState is used by GroupStateResponse for groupCopiesState
+type CopyState struct {
+	CopyUID            CopyUID  `json:"copyUID"`
+	Enabled            bool     `json:"enabled"`
+	Active             bool     `json:"active"`
+	JournalState       string   `json:"journalState"`
+	StorageAccessState string   `json:"storageAccessState"`
+	AccessedImage      Snapshot `json:"accessedImage"`
+}
+
+// Snapshot holds the point in time of an image within CopyState
+type Snapshot struct {
+	ClosingTimeStamp TimeStamp `json:"closingTimeStamp"`
+}
+
+// TimeStamp holds an api timestamp in microseconds since the unix epoch
+type TimeStamp struct {
+	TimeInMicroSeconds int64 `json:"timeInMicroSeconds"`
+}
+
+// LinkState is used by GroupStateResponse for linksState
+type LinkState struct {
+	GroupLinkUID GroupLinkUID `json:"groupLinkUID"`
+	PipeState    string       `json:"pipeState"`
+}
+
+// CopyStatistics is used by GroupStatisticsResponse for consistencyGroupCopyStatistics
+type CopyStatistics struct {
+	CopyUID           CopyUID           `json:"copyUID"`
+	JournalStatistics JournalStatistics `json:"journalStatistics"`
+}
+
+// JournalStatistics holds the journal usage & distribution lag of a copy
+type JournalStatistics struct {
+	ActualJournalUsageInBytes int64 `json:"actualJournalUsageInBytes"`
+	JournalCapacityInBytes    int64 `json:"journalCapacityInBytes"`
+	JournalLagInBytes         int64 `json:"journalLagInBytes"`
+}
+
+// LinkStatistics is used by GroupStatisticsResponse for consistencyGroupLinkStatistics
+type LinkStatistics struct {
+	GroupLinkUID         GroupLinkUID         `json:"groupLinkUID"`
+	ProtectionStatistics ProtectionStatistics `json:"protectionStatistics"`
+}
+
+// ProtectionStatistics holds the current lag of a link
+type ProtectionStatistics struct {
+	Lag Lag `json:"lag"`
+}
+
+// Lag holds the time (microseconds), data (bytes) & writes a replica is behind production
+type Lag struct {
+	TimeCounter   int64 `json:"timeCounter"`
+	DataCounter   int64 `json:"dataCounter"`
+	WritesCounter int64 `json:"writesCounter"`
+}
+
+// BookmarkPutData is used to marshal the required PUT data to create a bookmark
+type BookmarkPutData struct {
+	BookmarkName        string `json:"bookmarkName"`
+	ConsolidationPolicy string `json:"consolidationPolicy"`
+}
+
 // ImageAccessPutData is used to marshal the required PUT data to enable image access
 type ImageAccessPutData struct {
 	Mode     string `json:"mode"`
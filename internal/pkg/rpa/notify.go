@@ -0,0 +1,164 @@
+package rpa
+
+import (
+	"os"
+	"time"
+)
+
+// notification delivery limits
+const (
+	notifyQueueSize    = 64               // events queued before further events are dropped
+	notifyMaxFailures  = 3                // consecutive failures after which a notifier is skipped for the run
+	notifyFlushTimeout = 60 * time.Second // time waited for queued events to be delivered before exiting
+)
+
+// notifiers returns the configured notifiers
+func (c *Config) notifiers() []Notifier {
+	var notifiers []Notifier
+	for i := range c.Webhooks {
+		notifiers = append(notifiers, &c.Webhooks[i])
+	}
+	if c.Email.Host != "" {
+		notifiers = append(notifiers, &c.Email)
+	}
+	return notifiers
+}
+
+// newEvent populates an event of the current run. The caller must hold runMu.
+func (a *App) newEvent(event string) Event {
+	host, _ := os.Hostname()
+	e := Event{
+		Event:     event,
+		Time:      time.Now(),
+		Operator:  currentUser(),
+		Host:      host,
+		Ticket:    a.Config.Ticket,
+		Reason:    a.Config.Reason,
+		CheckMode: a.Config.CheckMode,
+	}
+	if a.run != nil {
+		e.RunID = a.run.ID
+		e.Command = a.run.Command
+	}
+	return e
+}
+
+// notifyRunStarted queues the run_started event. The caller must hold runMu.
+func (a *App) notifyRunStarted() {
+	e := a.newEvent("run_started")
+	for _, g := range a.run.Groups {
+		e.Groups = append(e.Groups, g.Name)
+	}
+	a.notify(e)
+}
+
+// notifyRunFinished queues the run_finished event with a summary of the run. The caller must hold runMu.
+func (a *App) notifyRunFinished() {
+	e := a.newEvent("run_finished")
+	s := &RunSummary{
+		Outcome:  "success",
+		Started:  a.run.Started,
+		Duration: a.run.Finished.Sub(a.run.Started),
+		Aborted:  !a.run.Completed,
+		Groups:   a.run.Groups,
+	}
+	for _, g := range a.run.Groups {
+		switch g.Status {
+		case groupDone:
+			s.Succeeded++
+		case groupFailed:
+			s.Failed++
+		default:
+			s.Pending++
+		}
+	}
+	if s.Failed > 0 || s.Pending > 0 || s.Aborted {
+		s.Outcome = "failure"
+	}
+	e.Summary = s
+	a.notify(e)
+}
+
+// notifyGroup queues the group_enabled, group_finished or group_failed event of an operation on a group
+func (a *App) notifyGroup(t Task, err error) {
+	a.runMu.Lock()
+	e := a.newEvent("group_finished")
+	a.runMu.Unlock()
+	if t.Enable {
+		e.Event = "group_enabled"
+	}
+	if err != nil {
+		e.Event = "group_failed"
+		e.Error = err.Error()
+	}
+	e.Group = t.GroupName
+	e.Copy = t.CopyName
+	a.notify(e)
+}
+
+// notify queues an event for delivery to the notifiers. Events are delivered in order by a single
+// worker so that slow or failing notifiers do not delay operations.
+func (a *App) notify(e Event) {
+	notifiers := a.Config.notifiers()
+	if len(notifiers) == 0 {
+		return
+	}
+	a.notifyMu.Lock()
+	defer a.notifyMu.Unlock()
+	if a.events == nil {
+		a.events = make(chan Event, notifyQueueSize)
+		a.notifyDone = make(chan struct{})
+		go deliverEvents(notifiers, a.events, a.notifyDone)
+	}
+	// the summary of the run is never dropped, the run is over so waiting does not delay operations
+	if e.Event == "run_finished" {
+		a.events <- e
+		return
+	}
+	// never block, the caller may hold runMu
+	select {
+	case a.events <- e:
+	default:
+		logEntry(e.RunID, e.Group, e.Copy).Warnf("Notification queue full, dropping %s notification", e.Event)
+	}
+}
+
+// deliverEvents delivers queued events to each notifier until the queue is closed. A notifier which
+// fails repeatedly is skipped for the remaining events.
+func deliverEvents(notifiers []Notifier, events chan Event, done chan struct{}) {
+	defer close(done)
+	failures := make([]int, len(notifiers))
+	for e := range events {
+		for i, n := range notifiers {
+			if failures[i] >= notifyMaxFailures {
+				continue
+			}
+			if err := n.Notify(e); err != nil {
+				logEntry(e.RunID, e.Group, e.Copy).Warnf("Unable to deliver %s notification: %s", e.Event, err)
+				failures[i]++
+				if failures[i] == notifyMaxFailures {
+					logEntry(e.RunID, "", "").Warnf("Skipping %s for the rest of the run after %d consecutive failures", n, notifyMaxFailures)
+				}
+				continue
+			}
+			failures[i] = 0
+		}
+	}
+}
+
+// flushNotifications waits (up to notifyFlushTimeout) until the queued events have been delivered
+func (a *App) flushNotifications() {
+	a.notifyMu.Lock()
+	events, done := a.events, a.notifyDone
+	a.events = nil
+	a.notifyMu.Unlock()
+	if events == nil {
+		return
+	}
+	close(events)
+	select {
+	case <-done:
+	case <-time.After(notifyFlushTimeout):
+		logEntry("", "", "").Warnf("Gave up waiting for notifications after %s, %d events were not delivered", notifyFlushTimeout, len(events))
+	}
+}
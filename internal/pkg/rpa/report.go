@@ -0,0 +1,301 @@
+package rpa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// reportFuncs are the functions available to the report & email templates
+var reportFuncs = map[string]interface{}{
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"time": func(t time.Time) string {
+		return t.Local().Format("2006-01-02 15:04:05")
+	},
+	"image": func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04:05")
+	},
+	"groupDuration": groupDuration,
+	"orDash":        displayState,
+	"copy":          describeCopy,
+	"cell":          markdownCell,
+	"trim": func(s string) string {
+		return strings.TrimRight(s, "\n")
+	},
+}
+
+// ReportFormat returns the format of a report from the extension of its path: html, md or json
+func ReportFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return "html", nil
+	case ".md", ".markdown":
+		return "md", nil
+	case ".json":
+		return "json", nil
+	}
+	return "", fmt.Errorf("unsupported report format '%s' (expected .html, .md or .json)", filepath.Ext(path))
+}
+
+// describeCopy summarizes the state of a copy (ie: direct_access, role ACTIVE, storage DIRECT_ACCESS)
+func describeCopy(c *CopySnapshot) string {
+	if c == nil {
+		return "not recorded"
+	}
+	parts := []string{copyAccessState(*c)}
+	if c.Role != "" {
+		parts = append(parts, "role "+c.Role)
+	}
+	if c.StorageAccessState != "" {
+		parts = append(parts, "storage "+c.StorageAccessState)
+	}
+	if c.TransferState != "" {
+		parts = append(parts, "transfer "+c.TransferState)
+	}
+	if c.ImageTimestamp != nil {
+		parts = append(parts, "image "+c.ImageTimestamp.Local().Format("2006-01-02 15:04:05"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// markdownCell escapes a value for use within a markdown table
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// recordState records the state of a group before it is first changed by a run with a report, along with
+// the image accessed on the copy about to be changed (ie: before it is finished by a runbook)
+func (a *App) recordState(groupID int, groupName, copyName string) {
+	if a.Report == "" {
+		return
+	}
+	gs, err := a.snapshotGroup(groupID, groupName)
+	if err != nil {
+		a.logger(groupName, copyName).Warnf("%s - Unable to record the state of the group for the report: %s", groupName, err)
+		return
+	}
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.reportBefore == nil {
+		a.reportBefore = make(map[string]GroupSnapshot)
+		a.reportImages = make(map[string]time.Time)
+	}
+	if _, ok := a.reportBefore[groupName]; !ok {
+		a.reportBefore[groupName] = gs
+	}
+	if c := copySnapshotByName(&gs, copyName); c != nil && c.ImageTimestamp != nil {
+		a.reportImages[groupName] = *c.ImageTimestamp
+	}
+}
+
+// buildReport collects the evidence of a run along with the current state of its groups
+func (a *App) buildReport(run *Run) Report {
+	host, _ := os.Hostname()
+	r := Report{
+		Generated:   time.Now(),
+		RunID:       run.ID,
+		Command:     run.Command,
+		CommandLine: strings.Join(os.Args, " "),
+		Operator:    currentUser(),
+		RPAUser:     a.Config.Username,
+		Host:        host,
+		Ticket:      run.Ticket,
+		Reason:      run.Reason,
+		CheckMode:   a.Config.CheckMode,
+		Started:     run.Started,
+		Finished:    run.Finished,
+		Duration:    run.Finished.Sub(run.Started),
+		Outcome:     "success",
+	}
+	if !run.Completed {
+		// the run was aborted by a fatal error
+		r.Outcome = "failure"
+	}
+	ids, err := a.getGroupIDsByName()
+	if err != nil {
+		logEntry(run.ID, "", "").Warnf("Unable to record the state of groups after the run: %s", err)
+	}
+	for _, g := range run.Groups {
+		if g.Status != groupDone {
+			r.Outcome = "failure"
+		}
+		gr := GroupReport{
+			Name:           g.Name,
+			Copy:           g.Copy,
+			Status:         g.Status,
+			Error:          g.Error,
+			WindowOverride: g.WindowOverride,
+			Duration:       groupDuration(g),
+			Steps:          g.Steps,
+		}
+		after := GroupSnapshot{Name: g.Name}
+		if id, ok := ids[g.Name]; ok {
+			if after, err = a.snapshotGroup(id, g.Name); err != nil {
+				logEntry(run.ID, g.Name, g.Copy).Warnf("%s - Unable to record the state of the group after the run: %s", g.Name, err)
+			}
+		}
+		gr.Copies = reportCopies(a.reportBefore[g.Name], after)
+		// the image accessed on the copy, after enabling or before finishing
+		if c := copySnapshotByName(&after, g.Copy); c != nil && c.ImageTimestamp != nil {
+			gr.Image = c.ImageTimestamp
+		} else if image, ok := a.reportImages[g.Name]; ok {
+			gr.Image = &image
+		}
+		r.Groups = append(r.Groups, gr)
+	}
+	return r
+}
+
+// reportCopies pairs the state of each copy of a group before & after a run
+func reportCopies(before, after GroupSnapshot) []CopyReport {
+	var copies []CopyReport
+	for i := range after.Copies {
+		c := CopyReport{Name: after.Copies[i].Name, After: &after.Copies[i]}
+		c.Before = copySnapshotByName(&before, c.Name)
+		copies = append(copies, c)
+	}
+	for i := range before.Copies {
+		if copySnapshotByName(&after, before.Copies[i].Name) == nil {
+			copies = append(copies, CopyReport{Name: before.Copies[i].Name, Before: &before.Copies[i]})
+		}
+	}
+	return copies
+}
+
+// writeReport writes the evidence report of a run in the format given by the extension of the report path
+func (a *App) writeReport(run *Run) {
+	if a.Report == "" || run == nil {
+		return
+	}
+	format, err := ReportFormat(a.Report)
+	if err != nil {
+		a.logger("", "").Error(err)
+		return
+	}
+	r := a.buildReport(run)
+	var b bytes.Buffer
+	switch format {
+	case "html":
+		err = reportHTML.Execute(&b, r)
+	case "md":
+		err = reportMarkdown.Execute(&b, r)
+	case "json":
+		var data []byte
+		data, err = json.MarshalIndent(r, "", "  ")
+		b.Write(data)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(a.Report, b.Bytes(), 0644)
+	}
+	if err != nil {
+		logEntry(run.ID, "", "").Errorf("Unable to write report %s: %s", a.Report, err)
+		return
+	}
+	fmt.Printf("Report written to %s\n", a.Report)
+}
+
+// reportMarkdown is the markdown version of the evidence report of a run
+var reportMarkdown = template.Must(template.New("md").Funcs(reportFuncs).Parse(`# DR Report: {{.Command}} run {{.RunID}}
+
+| | |
+|---|---|
+| Outcome | **{{.Outcome}}**{{if .CheckMode}} (check mode, no changes were made){{end}} |
+| Operator | {{cell .Operator}}@{{cell .Host}} (rpa user: {{cell .RPAUser}}) |
+| Ticket | {{cell (orDash .Ticket)}} |
+| Reason | {{cell (orDash .Reason)}} |
+| Command | ` + "`{{cell .CommandLine}}`" + ` |
+| Started | {{time .Started}} |
+| Finished | {{time .Finished}} |
+| Duration | {{duration .Duration}} |
+
+## Consistency Groups
+
+| Group | Copy | Status | Duration | Accessed Image | Error |
+|---|---|---|---|---|---|
+{{range .Groups}}| {{cell .Name}} | {{cell (orDash .Copy)}} | {{.Status}}{{if .WindowOverride}} (window overridden){{end}} | {{duration .Duration}} | {{image .Image}} | {{cell .Error}} |
+{{end}}
+{{- range .Groups}}
+## {{.Name}}
+
+| Copy | Before | After |
+|---|---|---|
+{{range .Copies}}| {{cell .Name}} | {{cell (copy .Before)}} | {{cell (copy .After)}} |
+{{end}}
+{{- if .Steps}}
+| Step | Started | Duration | Result |
+|---|---|---|---|
+{{range .Steps}}| {{.Name}} | {{time .Started}} | {{duration .Duration}} | {{if .Error}}failed: {{cell .Error}}{{else if not .Completed}}interrupted{{else}}ok{{end}} |
+{{end}}{{end}}
+{{- range .Steps}}{{if .Output}}
+Output of {{.Name}}:
+` + "```" + `
+{{trim .Output}}
+` + "```" + `
+{{end}}{{end}}{{end}}
+_Generated by rpda on {{time .Generated}}_
+`))
+
+// reportHTML is the self contained html version of the evidence report of a run
+var reportHTML = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>DR Report: {{.Command}} run {{.RunID}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+pre { background: #f6f6f6; border: 1px solid #ddd; padding: 8px; }
+.success, .done { color: #2e7d32; }
+.failure, .failed { color: #c62828; }
+</style>
+</head>
+<body>
+<h1>DR Report: {{.Command}} run {{.RunID}}</h1>
+<table>
+<tr><th>Outcome</th><td class="{{.Outcome}}"><b>{{.Outcome}}</b>{{if .CheckMode}} (check mode, no changes were made){{end}}</td></tr>
+<tr><th>Operator</th><td>{{.Operator}}@{{.Host}} (rpa user: {{.RPAUser}})</td></tr>
+<tr><th>Ticket</th><td>{{orDash .Ticket}}</td></tr>
+<tr><th>Reason</th><td>{{orDash .Reason}}</td></tr>
+<tr><th>Command</th><td><code>{{.CommandLine}}</code></td></tr>
+<tr><th>Started</th><td>{{time .Started}}</td></tr>
+<tr><th>Finished</th><td>{{time .Finished}}</td></tr>
+<tr><th>Duration</th><td>{{duration .Duration}}</td></tr>
+</table>
+<h2>Consistency Groups</h2>
+<table>
+<tr><th>Group</th><th>Copy</th><th>Status</th><th>Duration</th><th>Accessed Image</th><th>Error</th></tr>
+{{range .Groups}}<tr><td>{{.Name}}</td><td>{{orDash .Copy}}</td>
+<td class="{{.Status}}">{{.Status}}{{if .WindowOverride}} (window overridden){{end}}</td>
+<td>{{duration .Duration}}</td><td>{{image .Image}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+{{range .Groups}}<h2>{{.Name}}</h2>
+<table>
+<tr><th>Copy</th><th>Before</th><th>After</th></tr>
+{{range .Copies}}<tr><td>{{.Name}}</td><td>{{copy .Before}}</td><td>{{copy .After}}</td></tr>
+{{end}}</table>
+{{if .Steps}}<table>
+<tr><th>Step</th><th>Started</th><th>Duration</th><th>Result</th></tr>
+{{range .Steps}}<tr><td>{{.Name}}</td><td>{{time .Started}}</td><td>{{duration .Duration}}</td>
+<td>{{if .Error}}<span class="failed">failed: {{.Error}}</span>{{else if not .Completed}}<span class="failed">interrupted</span>{{else}}ok{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{range .Steps}}{{if .Output}}<p>Output of {{.Name}}:</p>
+<pre>{{trim .Output}}</pre>
+{{end}}{{end}}{{end}}<p><i>Generated by rpda on {{time .Generated}}</i></p>
+</body>
+</html>
+`))
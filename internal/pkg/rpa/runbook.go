@@ -0,0 +1,459 @@
+package rpa
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// runbook actions & failure policies
+var (
+	runbookActions  = []string{"bookmark", "enable", "wait", "exec", "pause", "finish"}
+	runbookPolicies = []string{"abort", "continue", "rollback"}
+)
+
+// plannedStage is a validated runbook stage along with the groups & copies it resolved to
+type plannedStage struct {
+	Stage
+	Number int
+	Groups []plannedGroup
+}
+
+// plannedGroup is a consistency group & the copy resolved for a runbook stage
+type plannedGroup struct {
+	ID   int
+	Name string
+	Task Task
+}
+
+// LoadRunbook reads a runbook from a yaml file
+func LoadRunbook(path string) *Runbook {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		log.Fatal(err)
+	}
+	rb := &Runbook{}
+	if err := v.Unmarshal(rb); err != nil {
+		log.Fatalf("Invalid runbook %s: %s", path, err)
+	}
+	if rb.Name == "" {
+		rb.Name = path
+	}
+	return rb
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// selectCopy sets the copy selection of the app from a runbook copy value ('test', 'dr' or a copy name)
+func (a *App) selectCopy(copy string) {
+	a.CopyName = ""
+	a.CopyRegexp = nil
+	switch copy {
+	case "test":
+		a.CopyRegexp = a.Identifiers.TestNodeRegexp
+	case "dr":
+		a.CopyRegexp = a.Identifiers.CopyNodeRegexp
+	default:
+		a.CopyName = copy
+	}
+}
+
+// planRunbook validates a runbook against the live group inventory and resolves the groups & copies of
+// each stage. All problems found are reported before exiting.
+func (a *App) planRunbook(rb *Runbook) []plannedStage {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// inventory of all groups by name (in api order)
+	groups, err := a.getAllGroups()
+	if err != nil {
+		log.Fatal(err)
+	}
+	groupNames, err := a.getGroupNames(groups)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var inventory []plannedGroup
+	for i, g := range groups {
+		inventory = append(inventory, plannedGroup{ID: g.ID, Name: groupNames[i]})
+	}
+
+	if len(rb.Stages) == 0 {
+		problem("runbook contains no stages")
+	}
+
+	var stages []plannedStage
+	for i, s := range rb.Stages {
+		ps := plannedStage{Stage: s, Number: i + 1}
+		if ps.Name == "" {
+			ps.Name = fmt.Sprintf("stage %d", ps.Number)
+		}
+		if ps.OnFailure == "" {
+			ps.OnFailure = "abort"
+		}
+		if !contains(runbookPolicies, ps.OnFailure) {
+			problem("%s: unknown on_failure policy '%s' (expected one of %s)",
+				ps.Name, ps.OnFailure, strings.Join(runbookPolicies, ", "))
+		}
+		if len(ps.Actions) == 0 {
+			problem("%s: no actions", ps.Name)
+		}
+
+		needsCopy := false
+		for _, action := range ps.Actions {
+			switch action.Action {
+			case "enable", "finish", "exec":
+				needsCopy = true
+			}
+			switch {
+			case !contains(runbookActions, action.Action):
+				problem("%s: unknown action '%s' (expected one of %s)",
+					ps.Name, action.Action, strings.Join(runbookActions, ", "))
+			case action.Action == "bookmark" && action.Name == "":
+				problem("%s: bookmark action requires a name", ps.Name)
+			case action.Action == "wait" && action.Duration <= 0:
+				problem("%s: wait action requires a duration", ps.Name)
+			case action.Action == "exec" && len(action.Command) == 0:
+				problem("%s: exec action requires a command", ps.Name)
+			}
+		}
+
+		// resolve groups by name & regexp
+		selected := make(map[string]bool)
+		for _, name := range s.Groups {
+			found := false
+			for _, g := range inventory {
+				if g.Name == name {
+					selected[g.Name] = true
+					found = true
+				}
+			}
+			if !found {
+				problem("%s: consistency group '%s' not found", ps.Name, name)
+			}
+		}
+		if ps.GroupRegexp != "" {
+			re, err := regexp.Compile(ps.GroupRegexp)
+			if err != nil {
+				problem("%s: invalid group_regexp: %s", ps.Name, err)
+			} else {
+				matched := false
+				for _, g := range inventory {
+					if re.MatchString(g.Name) {
+						selected[g.Name] = true
+						matched = true
+					}
+				}
+				if !matched {
+					problem("%s: group_regexp '%s' did not match any consistency group", ps.Name, ps.GroupRegexp)
+				}
+			}
+		}
+		if len(s.Groups) == 0 && ps.GroupRegexp == "" {
+			problem("%s: one of groups or group_regexp must be provided", ps.Name)
+		}
+		if needsCopy && ps.Copy == "" {
+			problem("%s: copy ('test', 'dr' or a copy name) is required for enable, finish & exec", ps.Name)
+		}
+
+		// resolve the copy of each group, keeping the order groups were listed in followed by regexp matches
+		a.selectCopy(ps.Copy)
+		var ordered []plannedGroup
+		for _, name := range s.Groups {
+			for _, g := range inventory {
+				if g.Name == name {
+					ordered = append(ordered, g)
+				}
+			}
+		}
+		for _, g := range inventory {
+			if selected[g.Name] && !contains(s.Groups, g.Name) {
+				ordered = append(ordered, g)
+			}
+		}
+		for _, g := range ordered {
+			groupCopiesSettings, err := a.getGroupCopiesSettings(g.ID)
+			if err != nil {
+				problem("%s: unable to read the copies of consistency group '%s': %s", ps.Name, g.Name, err)
+				continue
+			}
+			copySettings, ok := a.findRequestedCopy(groupCopiesSettings)
+			if !ok && ps.Copy != "" {
+				problem("%s: copy '%s' not found in consistency group '%s'", ps.Name, ps.Copy, g.Name)
+			}
+			if len(groupCopiesSettings) > 0 {
+				// bookmarks only require the group uid, which is shared by all copies
+				if !ok {
+					copySettings = groupCopiesSettings[0]
+					copySettings.Name = ""
+				}
+				g.Task = newTask(g.Name, copySettings, true)
+			}
+			ps.Groups = append(ps.Groups, g)
+		}
+		stages = append(stages, ps)
+	}
+
+	if len(problems) > 0 {
+		a.logger("", "").Errorf("Runbook '%s' failed validation:", rb.Name)
+		for _, p := range problems {
+			fmt.Println(" - ", p)
+		}
+		os.Exit(1)
+	}
+	return stages
+}
+
+func describeAction(action Action) string {
+	switch action.Action {
+	case "bookmark":
+		return fmt.Sprintf("bookmark \"%s\"", action.Name)
+	case "enable":
+		return "enable direct access"
+	case "finish":
+		return "finish direct access"
+	case "wait":
+		return fmt.Sprintf("wait %s", action.Duration)
+	case "exec":
+		return fmt.Sprintf("exec %s", strings.Join(action.Command, " "))
+	case "pause":
+		return fmt.Sprintf("pause \"%s\"", action.Message)
+	}
+	return action.Action
+}
+
+func (a *App) printPlan(rb *Runbook, stages []plannedStage) {
+	fmt.Printf("Runbook: %s (%d stages)\n", rb.Name, len(stages))
+	for _, s := range stages {
+		timeout := "none"
+		if s.Timeout > 0 {
+			timeout = s.Timeout.String()
+		}
+		fmt.Printf("Stage %d: %s (timeout: %s, on failure: %s)\n", s.Number, s.Name, timeout, s.OnFailure)
+		for _, g := range s.Groups {
+			if g.Task.CopyName != "" {
+				fmt.Printf("\t%s (copy: %s)\n", g.Name, g.Task.CopyName)
+			} else {
+				fmt.Printf("\t%s\n", g.Name)
+			}
+		}
+		for i, action := range s.Actions {
+			fmt.Printf("\t%d. %s\n", i+1, describeAction(action))
+		}
+	}
+}
+
+// RunRunbook validates & executes a runbook, returning a non-zero status when a stage failed.
+// In check mode the plan is displayed without making any changes. An interrupt (Ctrl-C) or SIGTERM
+// stops the stage in progress, which is then handled as a failed stage without continuing.
+func (a *App) RunRunbook(rb *Runbook) int {
+	start := time.Now()
+	stages := a.planRunbook(rb)
+	a.printPlan(rb, stages)
+	if a.Config.CheckMode {
+		fmt.Println("Check mode enabled, no changes were made")
+		return 0
+	}
+
+	owner := a.beginRun("run", nil)
+	defer a.endRun(owner)
+
+	// capture interrupts for the lifetime of the run so that the run always ends (and rolls back) cleanly
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			a.logger("", "").Warnf("Received %s, stopping the runbook", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	status := 0
+	var enabled []plannedGroup // groups enabled by this run which have not been finished
+stages:
+	for _, s := range stages {
+		err := a.runStage(ctx, rb, s, &enabled)
+		if err == nil {
+			continue
+		}
+		status = 1
+		a.logger("", "").Errorf("[%s] Stage failed: %s", s.Name, err)
+		switch {
+		case s.OnFailure == "continue" && ctx.Err() == nil:
+			a.logger("", "").Warnf("[%s] Continuing with the next stage", s.Name)
+		case s.OnFailure == "rollback":
+			a.logger("", "").Warnf("[%s] Rolling back %d consistency groups enabled by this run", s.Name, len(enabled))
+			a.rollbackRunbook(enabled)
+			break stages
+		default:
+			a.logger("", "").Warnf("[%s] Aborting runbook", s.Name)
+			break stages
+		}
+	}
+	elapsed := time.Since(start)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	return status
+}
+
+func (a *App) rollbackRunbook(enabled []plannedGroup) {
+	// finish in the reverse order groups were enabled
+	for i := len(enabled) - 1; i >= 0; i-- {
+		g := enabled[i]
+		a.selectCopy(g.Task.CopyName)
+		err := a.finishGroup(g.ID, g.Name)
+		if err != nil {
+			a.logger(g.Name, "").Errorf("%s - Rollback failed: %s", g.Name, err)
+		}
+	}
+}
+
+// stopped describes why the context of a stage is done
+func stopped(ctx context.Context, s plannedStage, when string, action Action) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timeout of %s reached %s '%s'", s.Timeout, when, describeAction(action))
+	}
+	return fmt.Errorf("interrupted %s '%s'", when, describeAction(action))
+}
+
+// runStage performs each action of a stage in order on all groups of the stage. The stage timeout applies
+// to every action, including the api requests & polling of bookmark, enable & finish.
+func (a *App) runStage(ctx context.Context, rb *Runbook, s plannedStage, enabled *[]plannedGroup) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	a.ctx = ctx
+	defer func() { a.ctx = nil }()
+	a.selectCopy(s.Copy)
+
+	for _, action := range s.Actions {
+		if ctx.Err() != nil {
+			return stopped(ctx, s, "before", action)
+		}
+		fmt.Printf("[%s] %s\n", s.Name, describeAction(action))
+
+		var failed []string
+		switch action.Action {
+		case "bookmark":
+			for _, g := range s.Groups {
+				if ctx.Err() != nil {
+					break
+				}
+				if err := a.createBookmark(g.Task, action.Name); err != nil {
+					a.logger(g.Name, "").Warnf("%s - %s", g.Name, err)
+					failed = append(failed, g.Name)
+				}
+			}
+		case "enable":
+			for _, g := range s.Groups {
+				if ctx.Err() != nil {
+					break
+				}
+				if err := a.enableGroup(g.ID, g.Name); err != nil {
+					a.logger(g.Name, "").Warnf("%s - %s", g.Name, err)
+					failed = append(failed, g.Name)
+					// a group stopped after image access was enabled must still be rolled back
+					if a.changedByRun(g.Name) {
+						*enabled = append(*enabled, g)
+					}
+					continue
+				}
+				*enabled = append(*enabled, g)
+			}
+		case "finish":
+			for _, g := range s.Groups {
+				if ctx.Err() != nil {
+					break
+				}
+				if err := a.finishGroup(g.ID, g.Name); err != nil {
+					a.logger(g.Name, "").Warnf("%s - %s", g.Name, err)
+					failed = append(failed, g.Name)
+					continue
+				}
+				for i := range *enabled {
+					if (*enabled)[i].ID == g.ID {
+						*enabled = append((*enabled)[:i], (*enabled)[i+1:]...)
+						break
+					}
+				}
+			}
+		case "wait":
+			select {
+			case <-time.After(action.Duration):
+			case <-ctx.Done():
+				return stopped(ctx, s, "during", action)
+			}
+		case "exec":
+			env := append(os.Environ(),
+				"RPDA_RUNBOOK="+rb.Name,
+				"RPDA_STAGE="+s.Name,
+			)
+			var names []string
+			for _, g := range s.Groups {
+				names = append(names, g.Name)
+			}
+			env = append(env, "RPDA_GROUPS="+strings.Join(names, ","))
+			if len(s.Groups) == 1 {
+				env = append(env, a.execEnv(s.Groups[0].ID, s.Groups[0].Task)...)
+			}
+			status := a.runCommand(ctx, s.Name, action.Command, env, nil)
+			if ctx.Err() != nil {
+				return stopped(ctx, s, "during", action)
+			}
+			if status != 0 {
+				return fmt.Errorf("'%s' exited with status %d", describeAction(action), status)
+			}
+		case "pause":
+			fmt.Printf("[%s] Paused: %s\nPress enter to continue or type 'abort' to stop: ", s.Name, action.Message)
+			answer := make(chan string, 1)
+			go func() {
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil {
+					line = "abort"
+				}
+				answer <- strings.TrimSpace(line)
+			}()
+			select {
+			case line := <-answer:
+				if line == "abort" {
+					return fmt.Errorf("aborted by operator")
+				}
+			case <-ctx.Done():
+				fmt.Println()
+				return stopped(ctx, s, "during", action)
+			}
+		}
+		if ctx.Err() != nil {
+			return stopped(ctx, s, "during", action)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("'%s' failed for: %s", describeAction(action), strings.Join(failed, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package rpa
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// copy access states compared when restoring a snapshot
+const (
+	accessReplicating  = "replicating"
+	accessImageAccess  = "image_access"
+	accessDirectAccess = "direct_access"
+)
+
+// storageDirectAccess is the storage access state of a copy in direct access
+const storageDirectAccess = "DIRECT_ACCESS"
+
+// snapshotChange is an operation required to return a copy to its state within a snapshot
+type snapshotChange struct {
+	GroupID   int
+	GroupName string
+	CopyName  string
+	From      string
+	To        string
+	Operation string // 'enable', 'finish' or '' when the change cannot be applied automatically
+}
+
+// copyAccessState describes whether a copy is replicating, in image access or in direct access
+func copyAccessState(c CopySnapshot) string {
+	if !c.ImageAccessEnabled {
+		return accessReplicating
+	}
+	if c.StorageAccessState == storageDirectAccess {
+		return accessDirectAccess
+	}
+	return accessImageAccess
+}
+
+// takeSnapshot records the current state of every copy of every consistency group
+func (a *App) takeSnapshot() (StatusSnapshot, error) {
+	s := StatusSnapshot{Taken: time.Now()}
+	groups, err := a.getAllGroups()
+	if err != nil {
+		return s, err
+	}
+	for _, g := range groups {
+		name, err := a.getGroupName(g.ID)
+		if err != nil {
+			return s, err
+		}
+		gs, err := a.snapshotGroup(g.ID, name)
+		if err != nil {
+			return s, err
+		}
+		s.Groups = append(s.Groups, gs)
+	}
+	return s, nil
+}
+
+// snapshotGroup records the current state of every copy of a consistency group
+func (a *App) snapshotGroup(groupID int, groupName string) (GroupSnapshot, error) {
+	gs := GroupSnapshot{Name: groupName}
+	state, err := a.getGroupState(groupID)
+	if err != nil {
+		return gs, err
+	}
+	groupCopiesSettings, err := a.getGroupCopiesSettings(groupID)
+	if err != nil {
+		return gs, err
+	}
+	for _, cs := range groupCopiesSettings {
+		uid := cs.CopyUID.GlobalCopyUID
+		c := CopySnapshot{
+			Name:               cs.Name,
+			ClusterUID:         uid.ClusterUID.ID,
+			CopyUID:            uid.CopyUID,
+			Role:               cs.RoleInfo.Role,
+			ImageAccessEnabled: cs.ImageAccessInformation.ImageAccessEnabled,
+			ImageAccessMode:    cs.ImageAccessInformation.ImageInformation.Mode,
+		}
+		for _, cst := range state.GroupCopiesState {
+			if cst.CopyUID.GlobalCopyUID == uid {
+				c.StorageAccessState = cst.StorageAccessState
+				if ts := cst.AccessedImage.ClosingTimeStamp.TimeInMicroSeconds; ts > 0 {
+					image := time.Unix(0, ts*int64(time.Microsecond))
+					c.ImageTimestamp = &image
+				}
+			}
+		}
+		for _, ls := range state.LinksState {
+			if ls.GroupLinkUID.SecondCopy == uid {
+				c.TransferState = ls.PipeState
+			}
+		}
+		gs.Copies = append(gs.Copies, c)
+	}
+	return gs, nil
+}
+
+// LoadSnapshot reads a status snapshot from a json file
+func LoadSnapshot(path string) (StatusSnapshot, error) {
+	var s StatusSnapshot
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("unable to read snapshot %s: %s", path, err)
+	}
+	return s, nil
+}
+
+// SaveSnapshot writes the current state of all consistency groups to a json file
+func (a *App) SaveSnapshot(path string) error {
+	s, err := a.takeSnapshot()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	copies := 0
+	for _, g := range s.Groups {
+		copies += len(g.Copies)
+	}
+	fmt.Printf("Saved the state of %d copies of %d consistency groups to %s\n", copies, len(s.Groups), path)
+	return nil
+}
+
+// snapshotChanges compares a snapshot with the current state and returns the changes required to
+// return each copy to the state recorded in the snapshot
+func (a *App) snapshotChanges(baseline, current StatusSnapshot) ([]snapshotChange, error) {
+	ids, err := a.getGroupIDsByName()
+	if err != nil {
+		return nil, err
+	}
+	var changes []snapshotChange
+	for _, bg := range baseline.Groups {
+		var cg *GroupSnapshot
+		for i := range current.Groups {
+			if current.Groups[i].Name == bg.Name {
+				cg = &current.Groups[i]
+			}
+		}
+		if cg == nil {
+			a.logger(bg.Name, "").Warnf("%s - Consistency group no longer exists, skipping", bg.Name)
+			continue
+		}
+		for _, bc := range bg.Copies {
+			var cc *CopySnapshot
+			for i := range cg.Copies {
+				if cg.Copies[i].Name == bc.Name {
+					cc = &cg.Copies[i]
+				}
+			}
+			if cc == nil {
+				a.logger(bg.Name, bc.Name).Warnf("%s - Copy %s no longer exists, skipping", bg.Name, bc.Name)
+				continue
+			}
+			from, to := copyAccessState(*cc), copyAccessState(bc)
+			if from == to {
+				continue
+			}
+			c := snapshotChange{
+				GroupID:   ids[bg.Name],
+				GroupName: bg.Name,
+				CopyName:  bc.Name,
+				From:      from,
+				To:        to,
+			}
+			switch {
+			case to == accessReplicating:
+				c.Operation = "finish"
+			case to == accessDirectAccess && from == accessReplicating:
+				c.Operation = "enable"
+			}
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// RestoreSnapshot applies the minimal set of enable & finish operations required to return all copies
+// to the state recorded in a snapshot. In check mode, only the differences are displayed.
+func (a *App) RestoreSnapshot(path string) error {
+	start := time.Now()
+	baseline, err := LoadSnapshot(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restoring snapshot %s (taken %s)\n", path, baseline.Taken.Format("2006-01-02 15:04:05"))
+	current, err := a.takeSnapshot()
+	if err != nil {
+		return err
+	}
+	changes, err := a.snapshotChanges(baseline, current)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("All copies match the snapshot, nothing to do")
+		return nil
+	}
+
+	// finish before enabling so that a group is never in direct access on two copies
+	var ordered []snapshotChange
+	manual := 0
+	for _, op := range []string{"finish", "enable", ""} {
+		for _, c := range changes {
+			if c.Operation == op {
+				ordered = append(ordered, c)
+			}
+		}
+	}
+	for _, c := range ordered {
+		operation := c.Operation
+		if operation == "" {
+			operation = "manual intervention required"
+			manual++
+		}
+		fmt.Printf("%s - %s: %s -> %s (%s)\n", c.GroupName, c.CopyName, c.From, c.To, operation)
+	}
+	if a.Config.CheckMode {
+		fmt.Println("Check mode enabled, no changes were made")
+		return nil
+	}
+
+	var groupNames []string
+	for _, c := range ordered {
+		if c.Operation != "" && !contains(groupNames, c.GroupName) {
+			groupNames = append(groupNames, c.GroupName)
+		}
+	}
+	owner := a.beginRun("restore", groupNames)
+	defer a.endRun(owner)
+	// record the planned operation of each group so that an interrupted restore can be resumed
+	for _, c := range ordered {
+		if c.Operation != "" {
+			a.startOperation(c.GroupName, c.Operation, c.CopyName)
+		}
+	}
+
+	failed := 0
+	for _, c := range ordered {
+		a.CopyName = c.CopyName
+		a.CopyRegexp = nil
+		switch c.Operation {
+		case "finish":
+			err = a.finishGroup(c.GroupID, c.GroupName)
+		case "enable":
+			err = a.enableGroup(c.GroupID, c.GroupName)
+		default:
+			continue
+		}
+		if err != nil {
+			a.logger(c.GroupName, "").Warnf("%s - %s\n", c.GroupName, err)
+			failed++
+		}
+	}
+	elapsed := time.Since(start)
+	a.logger("", "").Printf("Done. (took %s)\n", elapsed)
+	if failed > 0 {
+		return fmt.Errorf("%d copies could not be restored", failed)
+	}
+	if manual > 0 {
+		return errors.New("one or more copies require manual intervention")
+	}
+	return nil
+}
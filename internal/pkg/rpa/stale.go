@@ -0,0 +1,194 @@
+package rpa
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// staleCopy is a copy found in image access by the stale access audit
+type staleCopy struct {
+	Detail   CopyDetail
+	Since    time.Time     // when image access was enabled (zero when unknown)
+	Source   string        // where Since was found: audit (log), lease or image (the accessed image)
+	InAccess time.Duration // time in image access (0 when unknown)
+	ImageAge time.Duration // time since the accessed image (0 when unknown)
+	Free     int64         // journal space remaining in bytes
+	Lease    *Lease
+	Stale    bool
+}
+
+// copyKey identifies a copy of a consistency group
+type copyKey struct {
+	Group string
+	Copy  string
+}
+
+// accessStarts returns when image access was last enabled by rpda on each copy according to the audit
+// log. Copies on which image access was disabled afterwards are omitted.
+func (a *App) accessStarts() map[copyKey]time.Time {
+	starts := make(map[copyKey]time.Time)
+	for _, r := range a.loadAudit() {
+		if r.Outcome != "success" {
+			continue
+		}
+		key := copyKey{r.Group, r.Copy}
+		switch r.Action {
+		case "enable_image_access":
+			starts[key] = r.Time
+		case "disable_image_access":
+			delete(starts, key)
+		}
+	}
+	return starts
+}
+
+// findImageAccessCopies scans all groups for copies with image access enabled
+func (a *App) findImageAccessCopies(olderThan time.Duration) ([]staleCopy, error) {
+	leases, err := a.loadLeases()
+	if err != nil {
+		return nil, err
+	}
+	starts := a.accessStarts()
+	now := time.Now()
+	groups, err := a.getAllGroups()
+	if err != nil {
+		return nil, err
+	}
+	var copies []staleCopy
+	for _, g := range groups {
+		groupName, err := a.getGroupName(g.ID)
+		if err != nil {
+			return nil, err
+		}
+		details, err := a.getGroupCopyDetails(g.ID, groupName)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range details {
+			if !d.ImageAccessEnabled {
+				continue
+			}
+			c := staleCopy{Detail: d, Free: d.JournalSizeBytes - d.JournalUsedBytes}
+			if c.Free < 0 {
+				c.Free = 0
+			}
+			if !d.ImageTimestamp.IsZero() {
+				c.ImageAge = now.Sub(d.ImageTimestamp)
+			}
+			for i, l := range leases {
+				if l.Group == d.GroupName && l.Copy == d.Name {
+					c.Lease = &leases[i]
+				}
+			}
+			// the api does not report when image access was enabled, use the audit log or the lease. Copies
+			// enabled otherwise (ie: via the GUI) fall back to the age of the accessed image, which was taken
+			// before image access was enabled.
+			if since, ok := starts[copyKey{d.GroupName, d.Name}]; ok {
+				c.Since, c.Source = since, "audit"
+			} else if c.Lease != nil {
+				c.Since, c.Source = c.Lease.Created, "lease"
+			} else if !d.ImageTimestamp.IsZero() {
+				c.Since, c.Source = d.ImageTimestamp, "image"
+			}
+			if !c.Since.IsZero() {
+				c.InAccess = now.Sub(c.Since)
+				c.Stale = c.InAccess >= olderThan
+			} else {
+				// nothing tells how long the copy has been in access, report it rather than hide it
+				c.Stale = true
+			}
+			copies = append(copies, c)
+		}
+	}
+	return copies, nil
+}
+
+// staleSorts are the orders of the stale access report: age (longest in access first, unknown last),
+// journal (least space remaining first) or group
+var staleSorts = map[string]func(c []staleCopy) func(i, j int) bool{
+	"age": func(c []staleCopy) func(i, j int) bool {
+		return func(i, j int) bool { return c[i].InAccess > c[j].InAccess }
+	},
+	"journal": func(c []staleCopy) func(i, j int) bool {
+		return func(i, j int) bool { return c[i].Free < c[j].Free }
+	},
+	"group": func(c []staleCopy) func(i, j int) bool {
+		return func(i, j int) bool {
+			if c[i].Detail.GroupName == c[j].Detail.GroupName {
+				return c[i].Detail.Name < c[j].Detail.Name
+			}
+			return c[i].Detail.GroupName < c[j].Detail.GroupName
+		}
+	},
+}
+
+// AuditStale reports copies in image or direct access across all groups, including how long they have
+// been in access (from the audit log or lease when enabled by rpda, otherwise the age of the accessed
+// image), the age of the accessed image & the journal space remaining. Copies in access for at least
+// olderThan, or for an unknown time, are marked stale. The number of stale copies is returned.
+func (a *App) AuditStale(olderThan time.Duration, sortBy string) (int, error) {
+	less, ok := staleSorts[sortBy]
+	if !ok {
+		return 0, fmt.Errorf("invalid sort '%s' (valid: age, journal, group)", sortBy)
+	}
+	copies, err := a.findImageAccessCopies(olderThan)
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(copies, less(copies))
+	if len(copies) == 0 {
+		fmt.Println("No copies with image access enabled")
+		return 0, nil
+	}
+
+	stale := 0
+	fmt.Printf("%-24s %-24s %-14s %-18s %-10s %-20s %-6s %s\n", "GROUP", "COPY", "ACCESS", "IN ACCESS", "IMAGE AGE", "JOURNAL FREE", "STALE", "LEASE")
+	for _, c := range copies {
+		d := c.Detail
+		access := d.StorageAccessState
+		if access == "" {
+			access = d.ImageAccessMode
+		}
+		inAccess, isStale := "unknown", "no"
+		if !c.Since.IsZero() {
+			inAccess = fmt.Sprintf("%s (%s)", c.InAccess.Round(time.Minute), c.Source)
+		}
+		if c.Stale {
+			isStale = "yes"
+			stale++
+		}
+		imageAge := "unknown"
+		if !d.ImageTimestamp.IsZero() {
+			imageAge = c.ImageAge.Round(time.Minute).String()
+		}
+		free := formatBytes(c.Free)
+		if d.JournalSizeBytes > 0 {
+			free = fmt.Sprintf("%s (%.1f%%)", free, float64(c.Free)/float64(d.JournalSizeBytes)*100)
+		}
+		lease := "-"
+		if c.Lease != nil {
+			lease = c.Lease.Owner + " until " + c.Lease.Expires.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-24s %-24s %-14s %-18s %-10s %-20s %-6s %s\n", d.GroupName, d.Name, displayState(access), inAccess, imageAge, free, isStale, lease)
+	}
+	fmt.Printf("%d copies in image access, %d stale (in access for more than %s)\n", len(copies), stale, olderThan)
+	if n := countSource(copies, "image"); n > 0 {
+		fmt.Printf("%d copies were not enabled by rpda, their time in access is at most the age of the accessed image\n", n)
+	}
+	if n := countSource(copies, ""); n > 0 {
+		fmt.Printf("%d copies have been in access for an unknown time & are marked stale\n", n)
+	}
+	return stale, nil
+}
+
+// countSource returns the number of copies with the time in access found from a source ("" when unknown)
+func countSource(copies []staleCopy, source string) int {
+	n := 0
+	for _, c := range copies {
+		if c.Source == source {
+			n++
+		}
+	}
+	return n
+}
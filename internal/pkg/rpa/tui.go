@@ -0,0 +1,374 @@
+package rpa
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	ansiReverse = "\033[7m"
+	ansiBold    = "\033[1m"
+)
+
+// key presses recognised by the dashboard
+const (
+	keyUp = iota + 256
+	keyDown
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyInterrupt
+)
+
+// tuiGroup holds a consistency group & its copies as displayed by the dashboard
+type tuiGroup struct {
+	ID     int
+	Name   string
+	Copies []GroupCopiesSettings
+}
+
+// dashboard holds the state of the interactive terminal ui
+type dashboard struct {
+	app      *App
+	fd       int
+	state    *terminal.State
+	groups   []tuiGroup
+	filter   string
+	cursor   int
+	offset   int
+	group    *tuiGroup    // selected group when drilled into a group, nil when listing groups
+	copies   []CopyDetail // details of the selected group
+	message  string
+	editing  bool // true while typing a filter
+	quitting bool
+}
+
+// Dashboard starts a full screen terminal ui listing consistency groups & copies, allowing a group to be
+// inspected and direct access to be enabled/finished on a copy using the same operations as the cli.
+func (a *App) Dashboard() {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		log.Fatal("The dashboard requires an interactive terminal")
+	}
+	d := &dashboard{app: a, fd: fd}
+	if err := d.loadGroups(); err != nil {
+		log.Fatal(err)
+	}
+	d.raw()
+	defer d.restore()
+
+	for !d.quitting {
+		d.draw()
+		for _, key := range d.readKeys() {
+			d.handle(key)
+		}
+	}
+	fmt.Print(ansiClear)
+}
+
+func (d *dashboard) raw() {
+	state, err := terminal.MakeRaw(d.fd)
+	if err != nil {
+		log.Fatal(err)
+	}
+	d.state = state
+}
+
+func (d *dashboard) restore() {
+	if d.state != nil {
+		terminal.Restore(d.fd, d.state)
+		d.state = nil
+	}
+}
+
+// loadGroups loads all groups & their copies, keeping the previously loaded groups on failure
+func (d *dashboard) loadGroups() error {
+	fmt.Print(ansiClear + "Loading consistency groups..\r\n")
+	all, err := d.app.getAllGroups()
+	if err != nil {
+		return err
+	}
+	var groups []tuiGroup
+	for _, g := range all {
+		name, err := d.app.getGroupName(g.ID)
+		if err != nil {
+			return err
+		}
+		copies, err := d.app.getGroupCopiesSettings(g.ID)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, tuiGroup{ID: g.ID, Name: name, Copies: copies})
+	}
+	d.groups = groups
+	return nil
+}
+
+// loadCopies loads the copy details of the selected group, displaying any failure as the message
+func (d *dashboard) loadCopies() {
+	copies, err := d.app.getGroupCopyDetails(d.group.ID, d.group.Name)
+	if err != nil {
+		d.message = err.Error()
+		return
+	}
+	d.copies = copies
+}
+
+// visibleGroups returns the groups matching the current filter
+func (d *dashboard) visibleGroups() []tuiGroup {
+	var groups []tuiGroup
+	for _, g := range d.groups {
+		if strings.Contains(strings.ToLower(g.Name), strings.ToLower(d.filter)) {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+func (d *dashboard) rows() int {
+	if d.group != nil {
+		return len(d.copies)
+	}
+	return len(d.visibleGroups())
+}
+
+// readKeys reads the next input from the terminal, which may contain several keys when text is pasted
+func (d *dashboard) readKeys() []int {
+	buf := make([]byte, 64)
+	n, err := os.Stdin.Read(buf)
+	if err != nil || n == 0 {
+		return []int{keyInterrupt}
+	}
+	var keys []int
+	for i := 0; i < n; i++ {
+		switch {
+		case buf[i] == 27 && i+2 < n && buf[i+1] == '[' && buf[i+2] == 'A':
+			keys = append(keys, keyUp)
+			i += 2
+		case buf[i] == 27 && i+2 < n && buf[i+1] == '[' && buf[i+2] == 'B':
+			keys = append(keys, keyDown)
+			i += 2
+		case buf[i] == 27:
+			keys = append(keys, keyEscape)
+		case buf[i] == '\r' || buf[i] == '\n':
+			keys = append(keys, keyEnter)
+		case buf[i] == 127 || buf[i] == 8:
+			keys = append(keys, keyBackspace)
+		case buf[i] == 3:
+			keys = append(keys, keyInterrupt)
+		default:
+			keys = append(keys, int(buf[i]))
+		}
+	}
+	return keys
+}
+
+func (d *dashboard) handle(key int) {
+	d.message = ""
+	if key == keyInterrupt || d.quitting {
+		d.quitting = true
+		return
+	}
+
+	if d.editing {
+		switch key {
+		case keyEnter:
+			d.editing = false
+		case keyEscape:
+			d.editing = false
+			d.filter = ""
+		case keyBackspace:
+			if len(d.filter) > 0 {
+				d.filter = d.filter[:len(d.filter)-1]
+			}
+		default:
+			if key >= 32 && key < 127 {
+				d.filter += string(rune(key))
+			}
+		}
+		d.cursor, d.offset = 0, 0
+		return
+	}
+
+	switch key {
+	case keyUp, 'k':
+		if d.cursor > 0 {
+			d.cursor--
+		}
+	case keyDown, 'j':
+		if d.cursor < d.rows()-1 {
+			d.cursor++
+		}
+	case 'r':
+		if d.group != nil {
+			d.loadCopies()
+		} else if err := d.loadGroups(); err != nil {
+			d.message = err.Error()
+		}
+	case 'q', keyEscape:
+		if d.group != nil {
+			d.back()
+		} else {
+			d.quitting = true
+		}
+	}
+
+	if d.group == nil {
+		switch key {
+		case '/':
+			d.editing = true
+		case keyEnter:
+			groups := d.visibleGroups()
+			if d.cursor < len(groups) {
+				g := groups[d.cursor]
+				d.group = &g
+				d.cursor, d.offset = 0, 0
+				d.loadCopies()
+			}
+		}
+		return
+	}
+
+	switch key {
+	case 'e':
+		d.operate(true)
+	case 'f':
+		d.operate(false)
+	}
+}
+
+func (d *dashboard) back() {
+	// refresh the copies of the group in the list as an operation may have changed them
+	for i := range d.groups {
+		if d.groups[i].ID == d.group.ID {
+			if copies, err := d.app.getGroupCopiesSettings(d.group.ID); err == nil {
+				d.groups[i].Copies = copies
+			}
+		}
+	}
+	d.group = nil
+	d.copies = nil
+	d.cursor, d.offset = 0, 0
+}
+
+// operate enables (or finishes) direct access on the selected copy after confirmation.
+// The terminal is restored while the operation runs so output matches the cli.
+func (d *dashboard) operate(enable bool) {
+	if d.cursor >= len(d.copies) {
+		return
+	}
+	c := d.copies[d.cursor]
+	if d.app.Identifiers.ProductionNodeRegexp.MatchString(c.Name) {
+		d.message = "The production copy cannot be selected"
+		return
+	}
+	operation := "Finish direct access on"
+	if enable {
+		operation = "Enable direct access on"
+	}
+	d.draw()
+	fmt.Printf("\r\n%s%s %s (%s)? [y/N]%s ", ansiBold, operation, c.Name, d.group.Name, ansiReset)
+	if keys := d.readKeys(); keys[0] != 'y' && keys[0] != 'Y' {
+		d.message = "Cancelled"
+		return
+	}
+
+	d.restore()
+	fmt.Print(ansiClear)
+	d.app.Group = d.group.Name
+	d.app.CopyName = c.Name
+	d.app.CopyRegexp = nil
+	if enable {
+		d.app.EnableOne()
+	} else {
+		d.app.FinishOne()
+	}
+	fmt.Print("\nPress any key to return to the dashboard..")
+	d.raw()
+	d.readKeys()
+	d.loadCopies()
+}
+
+func (d *dashboard) draw() {
+	_, height, err := terminal.GetSize(d.fd)
+	if err != nil || height < 8 {
+		height = 24
+	}
+	visible := height - 5 // title, header, blank, message & help lines
+	if d.cursor < d.offset {
+		d.offset = d.cursor
+	}
+	if d.cursor >= d.offset+visible {
+		d.offset = d.cursor - visible + 1
+	}
+
+	var lines []string
+	var help string
+	if d.group == nil {
+		title := fmt.Sprintf("rpda - %d Consistency Groups", len(d.groups))
+		if d.filter != "" || d.editing {
+			title += fmt.Sprintf(" (filter: %s)", d.filter)
+		}
+		lines = append(lines, ansiBold+title+ansiReset, fmt.Sprintf("  %-40s %s", "GROUP", "COPIES"))
+		for i, g := range d.visibleGroups() {
+			var copies []string
+			for _, cs := range g.Copies {
+				state := cs.RoleInfo.Role
+				if cs.ImageAccessInformation.ImageAccessEnabled {
+					state += "/" + cs.ImageAccessInformation.ImageInformation.Mode
+				}
+				copies = append(copies, fmt.Sprintf("%s (%s)", cs.Name, state))
+			}
+			lines = append(lines, d.row(i, fmt.Sprintf("%-40s %s", g.Name, strings.Join(copies, ", "))))
+		}
+		help = "up/down: select  enter: open group  /: filter  r: refresh  q: quit"
+		if d.editing {
+			help = "type to filter  enter: apply  esc: clear"
+		}
+	} else {
+		lines = append(lines, ansiBold+"rpda - "+d.group.Name+ansiReset,
+			fmt.Sprintf("  %-30s %-8s %-14s %-14s %-10s %-8s %s",
+				"COPY", "ROLE", "IMAGE ACCESS", "STORAGE", "TRANSFER", "JOURNAL", "RPO (CURRENT/MAX)"))
+		for i, c := range d.copies {
+			rpo := "-"
+			if c.TransferState != "" {
+				rpo = fmt.Sprintf("%s/%s", c.CurrentRPO, c.ConfiguredRPO)
+			}
+			lines = append(lines, d.row(i, fmt.Sprintf("%-30s %-8s %-14s %-14s %-10s %-8s %s",
+				c.Name, c.Role, displayState(c.ImageAccessMode), displayState(c.StorageAccessState),
+				displayState(c.TransferState), fmt.Sprintf("%.1f%%", c.JournalUsage), rpo)))
+		}
+		help = "up/down: select  e: enable direct access  f: finish  r: refresh  q/esc: back"
+	}
+
+	// only draw the rows which fit on screen
+	header, body := lines[:2], lines[2:]
+	end := d.offset + visible
+	if end > len(body) {
+		end = len(body)
+	}
+	if d.offset < len(body) {
+		body = body[d.offset:end]
+	}
+
+	var b strings.Builder
+	b.WriteString(ansiClear)
+	b.WriteString(strings.Join(append(header, body...), "\r\n"))
+	b.WriteString("\r\n\r\n")
+	if d.message != "" {
+		b.WriteString(ansiHighlight + d.message + ansiReset)
+	}
+	b.WriteString("\r\n" + help)
+	fmt.Print(b.String())
+}
+
+func (d *dashboard) row(i int, text string) string {
+	if i == d.cursor {
+		return ansiReverse + "> " + text + ansiReset
+	}
+	return "  " + text
+}
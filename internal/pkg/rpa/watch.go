@@ -0,0 +1,182 @@
+package rpa
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	ansiClear     = "\033[H\033[2J"
+	ansiHighlight = "\033[1;33m"
+	ansiReset     = "\033[0m"
+)
+
+// watchedCopy tracks the state of a copy between watch refreshes
+type watchedCopy struct {
+	state   string
+	since   time.Time
+	changed bool
+	known   bool // false until a state change has been observed (since is the start of the watch)
+}
+
+// copySelected determines if a copy matches the copy selection (CopyName or CopyRegexp) of the app.
+// Production copies are never selected & all other copies are selected when no selection was made.
+func (a *App) copySelected(name string) bool {
+	if a.Identifiers.ProductionNodeRegexp.MatchString(name) {
+		return false
+	}
+	if a.CopyName != "" {
+		return name == a.CopyName
+	}
+	if a.CopyRegexp == nil {
+		return true
+	}
+	if !a.CopyRegexp.MatchString(name) {
+		return false
+	}
+	// the dr regexp may also match test copies, exclude them unless the test regexp was chosen
+	if a.CopyRegexp.String() != a.Identifiers.TestNodeRegexp.String() {
+		return !a.Identifiers.TestNodeRegexp.MatchString(name)
+	}
+	return true
+}
+
+// copyStateMatches determines if any of the role, image access mode, storage access state or
+// transfer state of a copy matches the provided state (ie: LOGGED_ACCESS)
+func copyStateMatches(d CopyDetail, state string) bool {
+	for _, s := range []string{d.Role, d.ImageAccessMode, d.StorageAccessState, d.TransferState} {
+		if strings.EqualFold(s, state) {
+			return true
+		}
+	}
+	return false
+}
+
+func displayState(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Watch displays the status of the selected copies every interval, highlighting copies which changed
+// state since the previous refresh. When until is provided, Watch returns once all selected copies
+// reach the state (on a refresh of every group). A timeout of 0 will watch indefinitely, otherwise Watch exits when reached.
+func (a *App) Watch(interval time.Duration, until string, timeout time.Duration) {
+	start := time.Now()
+	tty := terminal.IsTerminal(int(os.Stdout.Fd()))
+	watched := make(map[string]*watchedCopy)
+
+	groups := make(map[int]string)
+	var groupIDs []int
+	if a.Group != "" {
+		groupID, err := a.getGroupIDByName(a.Group)
+		if err != nil {
+			log.Fatal(err)
+		}
+		groups[groupID] = a.Group
+		groupIDs = append(groupIDs, groupID)
+	} else {
+		allGroups, err := a.getAllGroups()
+		if err != nil {
+			log.Fatal(err)
+		}
+		groupNames, err := a.getGroupNames(allGroups)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for i, g := range allGroups {
+			groups[g.ID] = groupNames[i]
+			groupIDs = append(groupIDs, g.ID)
+		}
+	}
+
+	for refresh := 1; ; refresh++ {
+		now := time.Now()
+		var out strings.Builder
+		selected, matched, failed := 0, 0, 0
+
+		for _, groupID := range groupIDs {
+			groupName := groups[groupID]
+			fmt.Fprintln(&out, groupName)
+			details, err := a.getGroupCopyDetails(groupID, groupName)
+			if err != nil {
+				// keep watching through transient api failures
+				fmt.Fprintf(&out, "\tunable to refresh: %s\n", err)
+				failed++
+				continue
+			}
+			for _, d := range details {
+				if !a.copySelected(d.Name) {
+					continue
+				}
+				selected++
+				if until != "" && copyStateMatches(d, until) {
+					matched++
+				}
+
+				key := groupName + "/" + d.Name
+				state := strings.Join([]string{d.Role, d.ImageAccessMode, d.StorageAccessState, d.TransferState}, "|")
+				w, ok := watched[key]
+				if !ok {
+					w = &watchedCopy{state: state, since: start}
+					watched[key] = w
+				}
+				w.changed = w.state != state
+				if w.changed {
+					w.state = state
+					w.since = now
+					w.known = true
+				}
+
+				inState := now.Sub(w.since).Round(time.Second).String()
+				if !w.known {
+					inState = ">" + inState
+				}
+				line := fmt.Sprintf("\t%-30s %-8s %-14s %-14s %-10s %s",
+					d.Name, d.Role, displayState(d.ImageAccessMode), displayState(d.StorageAccessState),
+					displayState(d.TransferState), inState)
+				if w.changed && tty {
+					line = ansiHighlight + line + ansiReset
+				} else if w.changed {
+					line = "*" + line
+				}
+				fmt.Fprintln(&out, line)
+			}
+		}
+
+		if tty {
+			fmt.Print(ansiClear)
+		}
+		fmt.Printf("Every %s - %s (refresh #%d, watching for %s)\n",
+			interval, now.Format("2006-01-02 15:04:05"), refresh, now.Sub(start).Round(time.Second))
+		if until != "" {
+			fmt.Printf("Waiting for %s: %d of %d copies", until, matched, selected)
+			if failed > 0 {
+				fmt.Printf(" (%d groups could not be refreshed)", failed)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("\t%-30s %-8s %-14s %-14s %-10s %s\n", "COPY", "ROLE", "IMAGE ACCESS", "STORAGE", "TRANSFER", "IN STATE")
+		fmt.Print(out.String())
+		if !tty {
+			fmt.Println("")
+		}
+
+		// the copies of groups which could not be refreshed are unknown, wait for a complete refresh
+		if until != "" && failed == 0 && selected > 0 && matched == selected {
+			fmt.Printf("All selected copies reached %s (took %s)\n", until, time.Since(start).Round(time.Second))
+			return
+		}
+		if timeout > 0 && time.Since(start) > timeout {
+			a.logger("", "").Errorf("Timeout reached after %s", timeout)
+			os.Exit(1)
+		}
+		time.Sleep(interval)
+	}
+}
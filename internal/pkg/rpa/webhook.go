@@ -0,0 +1,156 @@
+package rpa
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// defaults used when a webhook does not configure them
+const (
+	defaultWebhookRetries = 3
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+// webhookEvents are the events which may be posted to a webhook
+var webhookEvents = map[string]bool{
+	"run_started":    true,
+	"group_enabled":  true,
+	"group_finished": true,
+	"group_failed":   true,
+	"run_finished":   true,
+}
+
+// webhookFuncs are the functions available to webhook templates
+var webhookFuncs = template.FuncMap{
+	// json encodes a value, ie: {"text": {{json .Error}}}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// compile validates a webhook & prepares its template for use
+func (w *Webhook) compile() error {
+	if w.URL == "" {
+		return errors.New("a url is required")
+	}
+	for _, e := range w.Events {
+		if !webhookEvents[e] {
+			return fmt.Errorf("invalid event '%s'", e)
+		}
+	}
+	if w.Template != "" {
+		t, err := template.New(w.URL).Funcs(webhookFuncs).Parse(w.Template)
+		if err != nil {
+			return err
+		}
+		w.body = t
+	}
+	return nil
+}
+
+// wants reports whether an event is posted to the webhook
+func (w *Webhook) wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// render returns the body of the webhook for an event
+func (w *Webhook) render(e Event) ([]byte, error) {
+	if w.body == nil {
+		return json.Marshal(&e)
+	}
+	var b bytes.Buffer
+	if err := w.body.Execute(&b, e); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// String describes the webhook
+func (w *Webhook) String() string {
+	return "webhook " + w.URL
+}
+
+// Notify posts an event to the webhook, retrying with an increasing delay when the request fails or
+// the server responds with 429 (Too Many Requests) or a 5xx status
+func (w *Webhook) Notify(e Event) error {
+	if !w.wants(e.Event) {
+		return nil
+	}
+	body, err := w.render(e)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %s", w.URL, err)
+	}
+	retries := w.Retries
+	if retries <= 0 {
+		retries = defaultWebhookRetries
+	}
+	delay := time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(e.Event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= retries {
+			return fmt.Errorf("webhook %s: %s", w.URL, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post makes a single attempt to post a body, returning whether a failed attempt may be retried
+func (w *Webhook) post(event string, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rpda")
+	req.Header.Set("X-Rpda-Event", event)
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Rpda-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
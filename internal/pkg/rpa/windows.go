@@ -0,0 +1,142 @@
+package rpa
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// windowDays maps the day names of a maintenance window to weekdays
+var windowDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseClock parses a HH:MM time of day into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s' (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// compile validates a maintenance window & prepares it for use
+func (w *Window) compile() error {
+	var err error
+	if w.start, err = parseClock(w.Start); err != nil {
+		return err
+	}
+	if w.end, err = parseClock(w.End); err != nil {
+		return err
+	}
+	w.location = time.Local
+	if w.TimeZone != "" {
+		if w.location, err = time.LoadLocation(w.TimeZone); err != nil {
+			return err
+		}
+	}
+	w.weekdays = nil
+	for _, d := range w.Days {
+		name := strings.ToLower(d)
+		if len(name) > 3 {
+			name = name[:3] // allow full day names (ie: monday)
+		}
+		day, ok := windowDays[name]
+		if !ok {
+			return fmt.Errorf("invalid day '%s'", d)
+		}
+		w.weekdays = append(w.weekdays, day)
+	}
+	if w.pattern, err = regexp.Compile(w.Regexp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// onDay reports whether the window starts on the provided weekday
+func (w *Window) onDay(day time.Weekday) bool {
+	if len(w.weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesTo reports whether the window applies to a consistency group
+func (w *Window) appliesTo(groupName string) bool {
+	return w.pattern == nil || w.pattern.MatchString(groupName)
+}
+
+// open reports whether the window is open at the provided time. A window with the same start & end is
+// open for the whole day and a window ending before it starts spans midnight.
+func (w *Window) open(t time.Time) bool {
+	lt := t.In(w.location)
+	minute := lt.Hour()*60 + lt.Minute()
+	switch {
+	case w.start == w.end:
+		return w.onDay(lt.Weekday())
+	case w.start < w.end:
+		return w.onDay(lt.Weekday()) && minute >= w.start && minute < w.end
+	default:
+		yesterday := lt.AddDate(0, 0, -1).Weekday()
+		return (w.onDay(lt.Weekday()) && minute >= w.start) || (w.onDay(yesterday) && minute < w.end)
+	}
+}
+
+// describe returns a summary of the window (ie: weekend (sat,sun 22:00-06:00 Europe/London))
+func (w *Window) describe() string {
+	days := "daily"
+	if len(w.Days) > 0 {
+		days = strings.Join(w.Days, ",")
+	}
+	desc := fmt.Sprintf("%s %s-%s %s", days, w.Start, w.End, w.location)
+	if w.Name != "" {
+		desc = fmt.Sprintf("%s (%s)", w.Name, desc)
+	}
+	return desc
+}
+
+// checkWindow returns an error when maintenance windows are configured and none which apply to the group
+// are open, unless the window was overridden (with a reason) in which case the override is recorded
+func (a *App) checkWindow(groupName string) error {
+	if len(a.Config.Windows) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var windows []string
+	for i := range a.Config.Windows {
+		w := &a.Config.Windows[i]
+		if !w.appliesTo(groupName) {
+			continue
+		}
+		if w.open(now) {
+			return nil
+		}
+		windows = append(windows, w.describe())
+	}
+	if len(windows) == 0 {
+		windows = append(windows, "none apply to this group")
+	}
+	if !a.Config.OverrideWindow {
+		return fmt.Errorf("outside of an open maintenance window (windows: %s), use --override-window --reason to override", strings.Join(windows, "; "))
+	}
+	a.logger(groupName, "").Warnf("%s - Outside of an open maintenance window, overridden: %s", groupName, a.Config.Reason)
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if a.run != nil {
+		a.groupRun(groupName).WindowOverride = true
+		a.saveRun()
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package rpa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowCompile(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   Window
+		wantErr  bool
+		start    int
+		end      int
+		weekdays []time.Weekday
+	}{
+		{
+			name:   "daily",
+			window: Window{Start: "22:00", End: "06:00"},
+			start:  22 * 60,
+			end:    6 * 60,
+		},
+		{
+			name:     "short & full day names",
+			window:   Window{Days: []string{"sat", "Sunday"}, Start: "00:00", End: "23:59"},
+			start:    0,
+			end:      23*60 + 59,
+			weekdays: []time.Weekday{time.Saturday, time.Sunday},
+		},
+		{
+			name:    "invalid start",
+			window:  Window{Start: "25:00", End: "06:00"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid end",
+			window:  Window{Start: "22:00", End: "6pm"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid day",
+			window:  Window{Days: []string{"someday"}, Start: "22:00", End: "06:00"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid time zone",
+			window:  Window{Start: "22:00", End: "06:00", TimeZone: "Nowhere/Atlantis"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid regexp",
+			window:  Window{Start: "22:00", End: "06:00", Regexp: "("},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.window
+			err := w.compile()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if w.start != tt.start || w.end != tt.end {
+				t.Errorf("compile() start/end = %d/%d, want %d/%d", w.start, w.end, tt.start, tt.end)
+			}
+			if len(w.weekdays) != len(tt.weekdays) {
+				t.Fatalf("compile() weekdays = %v, want %v", w.weekdays, tt.weekdays)
+			}
+			for i := range tt.weekdays {
+				if w.weekdays[i] != tt.weekdays[i] {
+					t.Errorf("compile() weekdays = %v, want %v", w.weekdays, tt.weekdays)
+				}
+			}
+		})
+	}
+}
+
+func TestWindowOpen(t *testing.T) {
+	// 2020-04-17 is a friday
+	at := func(day int, clock string) time.Time {
+		c, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(2020, 4, day, c.Hour(), c.Minute(), 0, 0, time.UTC)
+	}
+	weekend := Window{Days: []string{"sat", "sun"}, Start: "22:00", End: "06:00", TimeZone: "UTC"}
+	office := Window{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", TimeZone: "UTC"}
+	allDay := Window{Days: []string{"sun"}, Start: "00:00", End: "00:00", TimeZone: "UTC"}
+	nightly := Window{Start: "23:00", End: "01:00", TimeZone: "UTC"}
+
+	tests := []struct {
+		name   string
+		window Window
+		time   time.Time
+		want   bool
+	}{
+		{"weekend friday night", weekend, at(17, "23:00"), false},
+		{"weekend saturday before start", weekend, at(18, "21:59"), false},
+		{"weekend saturday at start", weekend, at(18, "22:00"), true},
+		{"weekend after midnight into sunday", weekend, at(19, "05:59"), true},
+		{"weekend sunday at end", weekend, at(19, "06:00"), false},
+		{"weekend sunday night", weekend, at(19, "23:30"), true},
+		{"weekend after midnight into monday", weekend, at(20, "03:00"), true},
+		{"weekend after midnight into saturday", weekend, at(18, "03:00"), false},
+		{"office friday", office, at(17, "12:00"), true},
+		{"office friday at end", office, at(17, "17:00"), false},
+		{"office saturday", office, at(18, "12:00"), false},
+		{"all day sunday", allDay, at(19, "00:00"), true},
+		{"all day sunday night", allDay, at(19, "23:59"), true},
+		{"all day monday", allDay, at(20, "00:00"), false},
+		{"nightly before midnight", nightly, at(17, "23:30"), true},
+		{"nightly after midnight", nightly, at(18, "00:30"), true},
+		{"nightly during the day", nightly, at(18, "12:00"), false},
+		{"other time zone", weekend, time.Date(2020, 4, 18, 23, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.window
+			if err := w.compile(); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.open(tt.time); got != tt.want {
+				t.Errorf("open(%s) = %v, want %v", tt.time.Format("Mon 15:04 MST"), got, tt.want)
+			}
+		})
+	}
+}